@@ -0,0 +1,90 @@
+package gozero
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/gozero/audit"
+	"github.com/projectdiscovery/gozero/internal/telemetry"
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// engineVersionTimeout is the max time spent asking the engine for its version
+const engineVersionTimeout = 5 * time.Second
+
+// auditEval writes the audit record of an evaluation when auditing is enabled
+func (g *Gozero) auditEval(ctx context.Context, started time.Time, backend string, config any, src, input *Source, args []string, res *types.Result, execErr error) error {
+	if g.Options.Audit == nil {
+		return nil
+	}
+	record := &audit.Record{
+		Timestamp:  started.UTC(),
+		Engine:     g.Options.engine,
+		Args:       args,
+		Backend:    backend,
+		DurationMS: time.Since(started).Milliseconds(),
+	}
+	switch backend {
+	case telemetry.BackendLocal, telemetry.BackendBubblewrap, telemetry.BackendSystemd:
+		// other backends (e.g. docker) do not run the engine of the host
+		record.EngineVersion = g.engineVersion(ctx)
+	}
+	// the record is written even when parts of it cannot be computed
+	var notes []string
+	if execErr != nil {
+		notes = append(notes, execErr.Error())
+	}
+	if srcContent, err := src.ReadAll(); err == nil {
+		record.SourceSHA256 = audit.SHA256(srcContent)
+	} else {
+		notes = append(notes, "audit: failed to read source: "+err.Error())
+	}
+	if config != nil {
+		if digest, err := audit.Digest(config); err == nil {
+			record.ConfigDigest = digest
+		} else {
+			notes = append(notes, "audit: failed to digest config: "+err.Error())
+		}
+	}
+	for _, source := range []*Source{src, input} {
+		if source == nil {
+			continue
+		}
+		for _, v := range source.Variables {
+			record.Variables = append(record.Variables, g.Options.Audit.Redact(v.Name, v.Value))
+		}
+	}
+	if res != nil {
+		record.ExitCode = res.GetExitCode()
+		record.StdoutSHA256 = audit.SHA256(res.RawStdout())
+		record.StderrSHA256 = audit.SHA256(res.RawStderr())
+	}
+	record.Error = strings.Join(notes, "; ")
+	return g.Options.Audit.Log(record)
+}
+
+// engineVersion returns the first line printed by `engine --version`
+// the value is computed once and cached
+func (g *Gozero) engineVersion(ctx context.Context) string {
+	g.versionOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), engineVersionTimeout)
+		defer cancel()
+		// some engines (e.g. python2) print their version to stderr
+		out, err := exec.CommandContext(ctx, g.Options.engine, "--version").CombinedOutput()
+		if err != nil {
+			return
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				g.version = line
+				return
+			}
+		}
+	})
+	return g.version
+}
@@ -0,0 +1,262 @@
+// audit package implements a tamper-evident JSONL audit log of executions.
+//
+// Every record carries the hash of the previous record so that removing,
+// reordering or editing a record breaks the chain and is detected by Verify.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// RedactedValue replaces variable values in audit records
+const RedactedValue = "[REDACTED]"
+
+var (
+	// ErrChainBroken is returned when a record does not reference the previous record hash
+	ErrChainBroken = errors.New("audit chain broken")
+	// ErrHashMismatch is returned when a record content does not match its hash
+	ErrHashMismatch = errors.New("audit record hash mismatch")
+)
+
+// Variable is a variable passed to an execution with its value redacted
+type Variable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Record is a single audit log entry describing one execution
+type Record struct {
+	Timestamp     time.Time  `json:"timestamp"`
+	SourceSHA256  string     `json:"source_sha256"`
+	Engine        string     `json:"engine"`
+	EngineVersion string     `json:"engine_version,omitempty"`
+	Args          []string   `json:"args,omitempty"`
+	Backend       string     `json:"backend"`
+	ConfigDigest  string     `json:"config_digest,omitempty"`
+	Variables     []Variable `json:"variables,omitempty"`
+	ExitCode      int        `json:"exit_code"`
+	Error         string     `json:"error,omitempty"`
+	DurationMS    int64      `json:"duration_ms"`
+	StdoutSHA256  string     `json:"stdout_sha256"`
+	StderrSHA256  string     `json:"stderr_sha256"`
+	PrevHash      string     `json:"prev_hash"`
+	Hash          string     `json:"hash"`
+}
+
+// computeHash returns the hash of the record with the Hash field unset
+func (r Record) computeHash() (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	return SHA256(data), nil
+}
+
+// Logger appends chained records to an audit log file.
+// It is safe for concurrent use.
+type Logger struct {
+	mu       sync.Mutex
+	file     *os.File
+	lastHash string
+	redact   func(name, value string) string
+}
+
+// Option configures a Logger
+type Option func(*Logger)
+
+// WithRedactor sets the function used to redact variable values.
+// By default all values are replaced with RedactedValue
+func WithRedactor(redact func(name, value string) string) Option {
+	return func(l *Logger) {
+		l.redact = redact
+	}
+}
+
+// NewLogger opens (or creates) the audit log at path. When the log already
+// contains records the chain continues from the last one
+func NewLogger(path string, opts ...Option) (*Logger, error) {
+	lastHash, err := lastRecordHash(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l := &Logger{
+		file:     file,
+		lastHash: lastHash,
+		redact: func(_, _ string) string {
+			return RedactedValue
+		},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, nil
+}
+
+// Redact returns the redacted form of a variable
+func (l *Logger) Redact(name, value string) Variable {
+	return Variable{Name: name, Value: l.redact(name, value)}
+}
+
+// Log chains the record to the previous one and appends it to the log
+func (l *Logger) Log(record *Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now().UTC()
+	}
+	record.PrevHash = l.lastHash
+	hash, err := record.computeHash()
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := l.file.Write(data); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.lastHash = hash
+	return nil
+}
+
+// Close closes the underlying log file
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// VerifyError describes the first invalid record found in a log
+type VerifyError struct {
+	Line int
+	Err  error
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// Verify checks the integrity of the audit log at path and returns
+// the number of valid records. A *VerifyError is returned for the
+// first record which is malformed, altered or out of chain
+func Verify(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return VerifyReader(file)
+}
+
+// VerifyReader is like Verify but reads the log from r
+func VerifyReader(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var prevHash string
+	count := 0
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return count, &VerifyError{Line: line, Err: err}
+		}
+		if record.PrevHash != prevHash {
+			return count, &VerifyError{Line: line, Err: ErrChainBroken}
+		}
+		hash, err := record.computeHash()
+		if err != nil {
+			return count, &VerifyError{Line: line, Err: err}
+		}
+		if hash != record.Hash {
+			return count, &VerifyError{Line: line, Err: ErrHashMismatch}
+		}
+		prevHash = record.Hash
+		count++
+	}
+	return count, scanner.Err()
+}
+
+// lastRecordHash returns the hash of the last record in the log at path
+func lastRecordHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var last []byte
+	for scanner.Scan() {
+		if data := bytes.TrimSpace(scanner.Bytes()); len(data) > 0 {
+			last = append(last[:0], data...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if len(last) == 0 {
+		return "", nil
+	}
+	var record Record
+	if err := json.Unmarshal(last, &record); err != nil {
+		return "", fmt.Errorf("could not parse last audit record: %w", err)
+	}
+	return record.Hash, nil
+}
+
+// SHA256 returns the hex encoded sha256 digest of data
+func SHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Digest returns the hex encoded sha256 digest of the JSON encoding of v.
+// It is used to fingerprint sandbox configurations
+func Digest(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return SHA256(data), nil
+}
@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	logger, err := NewLogger(path)
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		err := logger.Log(&Record{
+			SourceSHA256: SHA256([]byte("print(1)")),
+			Engine:       "/usr/bin/python3",
+			Backend:      "local",
+			Variables:    []Variable{logger.Redact("TOKEN", "secret")},
+		})
+		require.Nil(t, err)
+	}
+	require.Nil(t, logger.Close())
+
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	require.NotContains(t, string(data), "secret")
+
+	count, err := Verify(path)
+	require.Nil(t, err)
+	require.Equal(t, 3, count)
+
+	// reopening the log continues the chain
+	logger, err = NewLogger(path)
+	require.Nil(t, err)
+	require.Nil(t, logger.Log(&Record{Engine: "node", Backend: "docker"}))
+	require.Nil(t, logger.Close())
+	count, err = Verify(path)
+	require.Nil(t, err)
+	require.Equal(t, 4, count)
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err := NewLogger(path)
+	require.Nil(t, err)
+	for _, code := range []int{0, 1, 2} {
+		require.Nil(t, logger.Log(&Record{Engine: "bash", Backend: "local", ExitCode: code}))
+	}
+	require.Nil(t, logger.Close())
+
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	// edited record
+	edited := strings.Replace(lines[1], `"exit_code":1`, `"exit_code":0`, 1)
+	require.Nil(t, os.WriteFile(path, []byte(strings.Join([]string{lines[0], edited, lines[2]}, "\n")), 0600))
+	count, err := Verify(path)
+	require.ErrorIs(t, err, ErrHashMismatch)
+	require.Equal(t, 1, count)
+	var verr *VerifyError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, 2, verr.Line)
+
+	// removed record
+	require.Nil(t, os.WriteFile(path, []byte(strings.Join([]string{lines[0], lines[2]}, "\n")), 0600))
+	_, err = Verify(path)
+	require.ErrorIs(t, err, ErrChainBroken)
+}
@@ -0,0 +1,84 @@
+// gozero is a command-line tool to run code through gozero engines and sandboxes.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// Exit codes used when the script itself did not produce one
+const (
+	exitTimeout = 124 // same as coreutils timeout
+	exitFailure = 125 // gozero failed before or while running the script
+	exitUsage   = 2
+)
+
+const usage = `usage: gozero <command> [flags]
+
+commands:
+  run    run a script with an engine, optionally in a sandbox
+  serve  serve the execution http api
+
+run 'gozero <command> -h' for the flags of a command
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run dispatches the command line to a subcommand and returns the process exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	switch args[0] {
+	case "run":
+		return runCommand(ctx, args[1:], stdin, stdout, stderr)
+	case "serve":
+		return serveCommand(ctx, args[1:], stderr)
+	case "-h", "-help", "--help", "help":
+		_, _ = fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		_, _ = fmt.Fprintf(stderr, "gozero: unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+// exitCode maps the outcome of an execution to the exit code of the
+// cli, mirroring the exit code of the script whenever there is one
+func exitCode(res *types.Result, err error) int {
+	var killed *types.KilledError
+	var nonZero *types.NonZeroExitError
+	switch {
+	case err == nil:
+		if res != nil {
+			return res.GetExitCode()
+		}
+		return 0
+	case errors.Is(err, types.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case errors.As(err, &killed):
+		if sig, ok := killed.Signal.(syscall.Signal); ok {
+			return 128 + int(sig)
+		}
+		return exitFailure
+	case errors.As(err, &nonZero):
+		return nonZero.Code
+	case res != nil && res.GetExitCode() > 0:
+		return res.GetExitCode()
+	default:
+		return exitFailure
+	}
+}
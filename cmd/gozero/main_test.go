@@ -0,0 +1,84 @@
+//go:build !windows
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunExitCode(t *testing.T) {
+	code, stdout, stderr := runCLI(t, "echo out; echo err >&2; exit 3", "run", "--lang", "sh")
+	require.Equal(t, 3, code)
+	require.Equal(t, "out\n", stdout)
+	require.Equal(t, "err\n", stderr)
+}
+
+func TestRunScriptFile(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`echo "$NAME $1"; cat`), 0644))
+	input := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(input, []byte("from input\n"), 0644))
+
+	code, stdout, _ := runCLI(t, "", "run", "--engine", "missing-engine,sh", "--var", "NAME=gozero", "--input", input, script, "arg")
+	require.Equal(t, 0, code)
+	require.Equal(t, "gozero arg\nfrom input\n", stdout)
+}
+
+func TestRunJSON(t *testing.T) {
+	code, stdout, _ := runCLI(t, "echo hello; exit 1", "run", "--lang", "sh", "--json")
+	require.Equal(t, 1, code)
+
+	var res struct {
+		ExitCode int    `json:"exit_code"`
+		Stdout   string `json:"stdout"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &res))
+	require.Equal(t, 1, res.ExitCode)
+	require.Equal(t, "hello\n", res.Stdout)
+}
+
+func TestRunTimeout(t *testing.T) {
+	code, _, stderr := runCLI(t, "exec sleep 5", "run", "--lang", "sh", "--timeout", "100ms")
+	require.Equal(t, exitTimeout, code)
+	require.Contains(t, stderr, "timed out")
+}
+
+func TestRunUsage(t *testing.T) {
+	code, _, _ := runCLI(t, "", "run", "--sandbox", "none", "--memory", "1g", "--lang", "sh")
+	require.Equal(t, exitUsage, code)
+
+	code, _, stderr := runCLI(t, "", "run", "--lang", "cobol")
+	require.Equal(t, exitUsage, code)
+	require.Contains(t, stderr, "unknown language")
+
+	code, _, _ = runCLI(t, "", "unknown")
+	require.Equal(t, exitUsage, code)
+}
+
+func TestServeUsage(t *testing.T) {
+	// sandboxes must be selected explicitly, including running unsandboxed
+	code, _, stderr := runCLI(t, "", "serve", "--insecure")
+	require.Equal(t, exitUsage, code)
+	require.Contains(t, stderr, "--sandboxes is required")
+
+	opts := &serveOptions{insecure: true, recovery: "requeue", sandboxes: "docker,none"}
+	config, err := opts.config()
+	require.NoError(t, err)
+	require.Equal(t, "docker", config.Profiles["default"].Backend)
+	require.Equal(t, "none", config.Profiles["none"].Backend)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/gozero/internal/runner"
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// varsFlag collects repeated --var name=value flags
+type varsFlag []types.Variable
+
+func (v *varsFlag) String() string {
+	parts := make([]string, 0, len(*v))
+	for _, variable := range *v {
+		parts = append(parts, variable.Name+"="+variable.Value)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (v *varsFlag) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid variable %q (expected name=value)", value)
+	}
+	*v = append(*v, types.Variable{Name: name, Value: val})
+	return nil
+}
+
+// runOptions are the flags of the run command
+type runOptions struct {
+	engines string
+	lang    string
+	input   string
+	vars    varsFlag
+	sandbox string
+	image   string
+	memory  string
+	cpus    string
+	network string
+	timeout time.Duration
+	json    bool
+
+	script string
+	args   []string
+}
+
+func parseRunOptions(args []string, stderr io.Writer) (*runOptions, error) {
+	opts := &runOptions{}
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "usage: gozero run [flags] [script|-] [args...]\n\nreads the script from stdin when it is - or missing\n\nflags:\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&opts.engines, "engine", "", "comma separated engines to try in order (e.g. python3,python)")
+	fs.StringVar(&opts.lang, "lang", "", "language of the script: "+strings.Join(runner.LanguageNames(), ", "))
+	fs.StringVar(&opts.input, "input", "", "file passed to the script as stdin (- for stdin)")
+	fs.Var(&opts.vars, "var", "variable passed to the script environment as name=value (repeatable)")
+	fs.StringVar(&opts.sandbox, "sandbox", runner.BackendNone, "sandbox backend: "+strings.Join(runner.Backends, ", "))
+	fs.StringVar(&opts.image, "image", "", "docker image (defaults to an image for --lang)")
+	fs.StringVar(&opts.memory, "memory", "", "memory limit (e.g. 512m) for docker and systemd")
+	fs.StringVar(&opts.cpus, "cpus", "", "cpu limit (e.g. 0.5) for docker and systemd")
+	fs.StringVar(&opts.network, "network", "", "network of the sandbox (none disables networking, docker accepts a network mode)")
+	fs.DurationVar(&opts.timeout, "timeout", 0, "maximum execution time (e.g. 30s, 0 disables it)")
+	fs.BoolVar(&opts.json, "json", false, "print the result as json")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		opts.script = fs.Arg(0)
+		opts.args = fs.Args()[1:]
+	}
+	if opts.script == "" {
+		opts.script = "-"
+	}
+	return opts, opts.validate()
+}
+
+func (o *runOptions) validate() error {
+	if o.engines == "" && o.lang == "" {
+		return errors.New("one of --engine or --lang is required")
+	}
+	if o.script == "-" && o.input == "-" {
+		return errors.New("the script and --input cannot both be read from stdin")
+	}
+	return o.spec().Validate()
+}
+
+// spec returns the runner spec of the options, without source and input
+func (o *runOptions) spec() *runner.Spec {
+	spec := &runner.Spec{
+		Language:  o.lang,
+		Variables: o.vars,
+		Args:      o.args,
+		Backend:   o.sandbox,
+		Limits: runner.Limits{
+			Image:   o.image,
+			Memory:  o.memory,
+			CPUs:    o.cpus,
+			Network: o.network,
+		},
+	}
+	for _, engine := range strings.Split(o.engines, ",") {
+		if engine = strings.TrimSpace(engine); engine != "" {
+			spec.Engines = append(spec.Engines, engine)
+		}
+	}
+	if o.input != "" {
+		// the input is read by execute, this lets Validate check the backend accepts one
+		spec.Stdin = []byte{}
+	}
+	return spec
+}
+
+func runCommand(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	opts, err := parseRunOptions(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "gozero: %v\n", err)
+		return exitUsage
+	}
+
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	res, err := opts.execute(ctx, stdin)
+	if err := writeResult(opts, res, stdout, stderr); err != nil {
+		_, _ = fmt.Fprintf(stderr, "gozero: %v\n", err)
+		return exitFailure
+	}
+	code := exitCode(res, err)
+	// a failing script already explains itself on stderr
+	var nonZero *types.NonZeroExitError
+	if err != nil && !errors.As(err, &nonZero) {
+		_, _ = fmt.Fprintf(stderr, "gozero: %v\n", err)
+	}
+	return code
+}
+
+// execute runs the script with the selected backend
+func (o *runOptions) execute(ctx context.Context, stdin io.Reader) (*types.Result, error) {
+	spec := o.spec()
+	var err error
+	if spec.Source, err = readFile(o.script, stdin); err != nil {
+		return nil, fmt.Errorf("could not read script: %w", err)
+	}
+	spec.Stdin = nil
+	if o.input != "" {
+		if spec.Stdin, err = readFile(o.input, stdin); err != nil {
+			return nil, fmt.Errorf("could not read input: %w", err)
+		}
+	}
+	return runner.Run(ctx, spec)
+}
+
+// readFile reads path, or stdin when path is -
+func readFile(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// writeResult prints the output of the script, or the result as json
+func writeResult(opts *runOptions, res *types.Result, stdout, stderr io.Writer) error {
+	if res == nil {
+		return nil
+	}
+	if opts.json {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+	if _, err := stdout.Write(res.Stdout.Bytes()); err != nil {
+		return err
+	}
+	_, err := stderr.Write(res.Stderr.Bytes())
+	return err
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/gozero/internal/runner"
+	"github.com/projectdiscovery/gozero/server"
+)
+
+// tokensFlag collects repeated --token flags
+type tokensFlag []string
+
+func (t *tokensFlag) String() string {
+	return fmt.Sprintf("%d tokens", len(*t))
+}
+
+func (t *tokensFlag) Set(value string) error {
+	if value == "" {
+		return errors.New("empty token")
+	}
+	*t = append(*t, value)
+	return nil
+}
+
+// serveOptions are the flags of the serve command
+type serveOptions struct {
+	listen         string
+	tokens         tokensFlag
+	tokenFile      string
+	insecure       bool
+	sandboxes      string
+	maxConcurrency int
+	queueSize      int
+	maxTimeout     time.Duration
+	store          string
+	recovery       string
+	retention      time.Duration
+}
+
+func serveCommand(ctx context.Context, args []string, stderr io.Writer) int {
+	opts := &serveOptions{}
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "usage: gozero serve [flags]\n\nflags:\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&opts.listen, "listen", "127.0.0.1:8080", "address to listen on")
+	fs.Var(&opts.tokens, "token", "bearer token accepted by the server (repeatable)")
+	fs.StringVar(&opts.tokenFile, "token-file", "", "file with one bearer token per line")
+	fs.BoolVar(&opts.insecure, "insecure", false, "allow running without bearer tokens")
+	fs.StringVar(&opts.sandboxes, "sandboxes", "", "comma separated sandbox backends exposed as profiles, the first one is the default (required, none runs executions unsandboxed)")
+	fs.IntVar(&opts.maxConcurrency, "max-concurrency", 0, "maximum number of executions running at once (defaults to the number of cpus)")
+	fs.IntVar(&opts.queueSize, "queue-size", 0, "maximum number of executions waiting to run (default 100)")
+	fs.DurationVar(&opts.maxTimeout, "max-timeout", time.Minute, "maximum execution time (0 disables it)")
+	fs.StringVar(&opts.store, "store", "", "bbolt database persisting executions across restarts (in memory when empty)")
+	fs.StringVar(&opts.recovery, "recovery", "requeue", "what to do with executions interrupted by a restart: requeue, fail")
+	fs.DurationVar(&opts.retention, "retention", time.Hour, "how long finished executions are kept")
+	if err := fs.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		return exitUsage
+	}
+
+	config, err := opts.config()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "gozero: %v\n", err)
+		return exitUsage
+	}
+	if opts.store != "" {
+		store, err := server.NewBoltStore(opts.store)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "gozero: could not open store: %v\n", err)
+			return exitFailure
+		}
+		defer func() {
+			_ = store.Close()
+		}()
+		config.Store = store
+	}
+	srv, err := server.New(config)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "gozero: %v\n", err)
+		return exitUsage
+	}
+	defer func() {
+		_ = srv.Close()
+	}()
+
+	httpServer := &http.Server{
+		Addr:              opts.listen,
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+	_, _ = fmt.Fprintf(stderr, "gozero: listening on %s\n", opts.listen)
+
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err = httpServer.Shutdown(shutdownCtx)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		_, _ = fmt.Fprintf(stderr, "gozero: %v\n", err)
+		return exitFailure
+	}
+	return 0
+}
+
+// config returns the server configuration of the options
+func (o *serveOptions) config() (server.Config, error) {
+	config := server.Config{
+		Tokens:         o.tokens,
+		MaxConcurrency: o.maxConcurrency,
+		QueueSize:      o.queueSize,
+		MaxTimeout:     o.maxTimeout,
+		Retention:      o.retention,
+		Profiles:       map[string]server.Profile{},
+	}
+	switch o.recovery {
+	case "requeue":
+		config.Recovery = server.RecoverRequeue
+	case "fail":
+		config.Recovery = server.RecoverFail
+	default:
+		return config, fmt.Errorf("unknown recovery policy %q (supported: requeue, fail)", o.recovery)
+	}
+	if o.tokenFile != "" {
+		data, err := os.ReadFile(o.tokenFile)
+		if err != nil {
+			return config, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if token := strings.TrimSpace(line); token != "" {
+				config.Tokens = append(config.Tokens, token)
+			}
+		}
+	}
+	if len(config.Tokens) == 0 && !o.insecure {
+		return config, errors.New("a --token or --token-file is required (use --insecure to disable authentication)")
+	}
+	for _, backend := range strings.Split(o.sandboxes, ",") {
+		backend = strings.TrimSpace(backend)
+		if backend == "" {
+			continue
+		}
+		profile := server.Profile{Backend: backend}
+		config.Profiles[backend] = profile
+		if _, ok := config.Profiles[server.DefaultProfile]; !ok {
+			config.Profiles[server.DefaultProfile] = profile
+		}
+	}
+	if len(config.Profiles) == 0 {
+		return config, fmt.Errorf("--sandboxes is required (supported: %s), none runs executions unsandboxed", strings.Join(runner.Backends, ", "))
+	}
+	return config, nil
+}
@@ -0,0 +1,78 @@
+package cmdexec
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// ErrUnsupportedAttr is returned when a process attribute is not supported on the platform
+var ErrUnsupportedAttr = errors.New("process attribute not supported on this platform")
+
+// Credential is the user and groups the process runs as.
+// Changing credentials requires root privileges (CAP_SETUID and CAP_SETGID on linux)
+type Credential struct {
+	Uid    uint32
+	Gid    uint32
+	Groups []uint32 // supplementary groups, none when empty
+}
+
+// SetDir sets the working directory of the command.
+// The working directory of the current process is used when empty
+func (c *Command) SetDir(dir string) {
+	c.dir = dir
+}
+
+// SetExtraFiles sets open files inherited by the process in addition to
+// stdin, stdout and stderr. Entry i becomes file descriptor 3+i.
+// Not supported on windows
+func (c *Command) SetExtraFiles(files ...*os.File) {
+	c.extraFiles = files
+}
+
+// SetCredential sets the user and groups the process runs as (unix only)
+func (c *Command) SetCredential(credential *Credential) {
+	c.credential = credential
+}
+
+// SetSetsid sets whether the process starts in a new session, detached from
+// the controlling terminal and process group of the current process (unix only)
+func (c *Command) SetSetsid(setsid bool) {
+	c.setsid = setsid
+}
+
+// SetSysProcAttr sets the platform specific attributes the process starts with.
+// Attributes set with the other setters (e.g. SetCredential) take precedence
+func (c *Command) SetSysProcAttr(attr *syscall.SysProcAttr) {
+	c.sysProcAttr = attr
+}
+
+// setAttr sets the process attributes of cmd
+func (c *Command) setAttr(cmd *exec.Cmd) error {
+	attr, err := c.procAttr()
+	if err != nil {
+		return err
+	}
+	cmd.Dir = c.dir
+	cmd.ExtraFiles = c.extraFiles
+	cmd.SysProcAttr = attr
+	return nil
+}
+
+// procAttr returns the platform specific attributes of the process,
+// nil when none are set
+func (c *Command) procAttr() (*syscall.SysProcAttr, error) {
+	if c.sysProcAttr == nil && c.credential == nil && !c.setsid && c.pdeathsig == 0 {
+		return nil, nil
+	}
+	attr := &syscall.SysProcAttr{}
+	if c.sysProcAttr != nil {
+		// the attributes are modified, the caller's are left untouched
+		*attr = *c.sysProcAttr
+	}
+	if err := c.setPlatformAttr(attr); err != nil {
+		return nil, err
+	}
+	return attr, nil
+}
@@ -0,0 +1,20 @@
+//go:build linux
+
+package cmdexec
+
+import "syscall"
+
+// SetPdeathsig sets the signal the process receives when the thread which
+// started it exits (e.g. syscall.SIGKILL so it does not outlive the current
+// process). Zero disables it
+func (c *Command) SetPdeathsig(sig syscall.Signal) {
+	c.pdeathsig = sig
+}
+
+// setLinuxAttr sets the linux specific attributes of the command in attr
+func (c *Command) setLinuxAttr(attr *syscall.SysProcAttr) error {
+	if c.pdeathsig != 0 {
+		attr.Pdeathsig = c.pdeathsig
+	}
+	return nil
+}
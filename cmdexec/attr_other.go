@@ -0,0 +1,10 @@
+//go:build unix && !linux
+
+package cmdexec
+
+import "syscall"
+
+// setLinuxAttr is a no-op, Pdeathsig can only be set on linux
+func (c *Command) setLinuxAttr(attr *syscall.SysProcAttr) error {
+	return nil
+}
@@ -0,0 +1,73 @@
+//go:build linux
+
+package cmdexec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandAttr(t *testing.T) {
+	for _, startProcess := range []bool{false, true} {
+		t.Run("startprocess="+strconv.FormatBool(startProcess), func(t *testing.T) {
+			dir := t.TempDir()
+			dir, err := filepath.EvalSymlinks(dir)
+			require.Nil(t, err)
+			extra, err := os.Create(filepath.Join(dir, "extra"))
+			require.Nil(t, err)
+			defer func() {
+				_ = extra.Close()
+			}()
+
+			// fd 3 is the extra file, the session and process ids are read from procfs
+			cmd, err := NewCommand("sh", "-c", `pwd; echo extra >&3; grep -E '^(NSsid|Pid):' /proc/$$/status | tr -s '\t' ' '`)
+			require.Nil(t, err)
+			cmd.SetPreferStartProcess(startProcess)
+			cmd.SetDir(dir)
+			cmd.SetExtraFiles(extra)
+			cmd.SetSetsid(true)
+			cmd.SetPdeathsig(syscall.SIGKILL)
+			custom := &syscall.SysProcAttr{Pdeathsig: syscall.SIGTERM}
+			cmd.SetSysProcAttr(custom)
+			res, err := cmd.Execute(context.Background())
+			require.Nil(t, err, res.Stderr.String())
+			require.False(t, custom.Setsid, "custom attributes must not be modified")
+
+			lines := strings.Split(strings.TrimSpace(res.Stdout.String()), "\n")
+			require.Equal(t, dir, lines[0])
+			status := map[string]string{}
+			for _, line := range lines[1:] {
+				name, value, _ := strings.Cut(line, ": ")
+				status[name] = strings.Fields(value)[0]
+			}
+			// a session leader's session id is its pid
+			require.Equal(t, status["Pid"], status["NSsid"])
+
+			content, err := os.ReadFile(extra.Name())
+			require.Nil(t, err)
+			require.Equal(t, "extra\n", string(content))
+		})
+	}
+}
+
+func TestCommandCredential(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing credentials requires root")
+	}
+	for _, startProcess := range []bool{false, true} {
+		cmd, err := NewCommand("sh", "-c", "id -u; id -g; id -G")
+		require.Nil(t, err)
+		cmd.SetPreferStartProcess(startProcess)
+		cmd.SetCredential(&Credential{Uid: 65534, Gid: 65534, Groups: []uint32{65534}})
+		res, err := cmd.Execute(context.Background())
+		require.Nil(t, err, res.Stderr.String())
+		require.Equal(t, "65534\n65534\n65534\n", res.Stdout.String())
+	}
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package cmdexec
+
+import "syscall"
+
+// setPlatformAttr sets the portable attributes of the command in attr
+func (c *Command) setPlatformAttr(attr *syscall.SysProcAttr) error {
+	if c.credential != nil {
+		attr.Credential = &syscall.Credential{
+			Uid:    c.credential.Uid,
+			Gid:    c.credential.Gid,
+			Groups: c.credential.Groups,
+		}
+	}
+	if c.setsid {
+		attr.Setsid = true
+	}
+	return c.setLinuxAttr(attr)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package cmdexec
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// setPlatformAttr returns ErrUnsupportedAttr for the unix only attributes
+func (c *Command) setPlatformAttr(attr *syscall.SysProcAttr) error {
+	switch {
+	case c.credential != nil:
+		return fmt.Errorf("%w: credential", ErrUnsupportedAttr)
+	case c.setsid:
+		return fmt.Errorf("%w: setsid", ErrUnsupportedAttr)
+	}
+	return nil
+}
@@ -5,28 +5,47 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"os"
 	"os/exec"
+	"syscall"
 
+	"github.com/projectdiscovery/gozero/internal/telemetry"
 	"github.com/projectdiscovery/gozero/types"
 	"github.com/projectdiscovery/utils/errkit"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Command is a command to execute.
 type Command struct {
-	Binary    string // Full path to the binary to execute
-	Args      []string
-	Env       []string
-	stdin     io.Reader
-	debugMode bool
+	Binary         string // Full path to the binary to execute
+	Args           []string
+	Env            []string
+	cleanEnv       bool
+	stdin          io.Reader
+	debugMode      bool
+	transcript     bool
+	tracerProvider trace.TracerProvider
+	pty            *PTYOptions
+	success        *types.SuccessCriteria
+	// preferStartProcess and symlinked select the os.StartProcess executor
+	preferStartProcess bool
+	symlinked          bool
+	// process attributes (see attr.go)
+	dir         string
+	extraFiles  []*os.File
+	credential  *Credential
+	setsid      bool
+	pdeathsig   syscall.Signal
+	sysProcAttr *syscall.SysProcAttr
 }
 
 // NewCommand creates a new command with the provided binary and arguments.
 func NewCommand(binary string, args ...string) (*Command, error) {
 	execpath, err := exec.LookPath(binary)
 	if err != nil {
-		return nil, err
+		return nil, types.StartError(err)
 	}
-	return &Command{Binary: execpath, Args: args}, nil
+	return &Command{Binary: execpath, Args: args, symlinked: isSymlink(execpath)}, nil
 }
 
 // SetEnv sets the environment variables for the command.
@@ -34,6 +53,12 @@ func (c *Command) SetEnv(env []string) {
 	c.Env = env
 }
 
+// SetCleanEnv sets whether the command starts without inheriting the
+// environment of the current process (only Env is passed).
+func (c *Command) SetCleanEnv(clean bool) {
+	c.cleanEnv = clean
+}
+
 // AddVars adds variables to the command.
 func (c *Command) AddVars(vars ...types.Variable) {
 	for _, v := range vars {
@@ -51,22 +76,47 @@ func (c *Command) EnableDebugMode() {
 	c.debugMode = true
 }
 
+// SetTranscript sets whether the result records a transcript of stdout and
+// stderr in the order they were read (see types.Transcript)
+func (c *Command) SetTranscript(enabled bool) {
+	c.transcript = enabled
+}
+
+// SetTracerProvider sets the tracer provider used to trace the execution.
+// When not set the global otel tracer provider is used.
+func (c *Command) SetTracerProvider(tp trace.TracerProvider) {
+	c.tracerProvider = tp
+}
+
+// SetSuccessCriteria sets the exit codes and stderr policy counting as success.
+// Executions satisfying the criteria return a nil error
+func (c *Command) SetSuccessCriteria(criteria *types.SuccessCriteria) {
+	c.success = criteria
+}
+
 // Execute executes the command and returns the output.
-func (c *Command) Execute(ctx context.Context) (*types.Result, error) {
+func (c *Command) Execute(ctx context.Context) (res *types.Result, err error) {
+	ctx, span := telemetry.Tracer(c.tracerProvider).Start(ctx, "cmdexec.Command.Execute",
+		trace.WithAttributes(telemetry.AttrEngine.String(c.Binary)),
+	)
+	defer func() {
+		err = c.success.Check(res, err)
+		telemetry.End(span, res, err)
+	}()
+
+	if c.useStartProcess() {
+		return c.executeStartProcess(ctx, &types.Result{Command: c.commandLine()})
+	}
 	cmd := exec.CommandContext(ctx, c.Binary, c.Args...)
-	if len(c.Env) > 0 {
-		// by default we allow existing environment variables to be inherited
-		cmd.Env = append(cmd.Environ(), c.Env...)
+	c.setEnv(cmd)
+	res = &types.Result{Command: cmd.String()}
+	if err := c.setAttr(cmd); err != nil {
+		return res, types.StartError(err)
 	}
-	res := &types.Result{Command: cmd.String()}
-	if c.debugMode {
-		res.DebugData = &bytes.Buffer{}
-		cmd.Stdout = io.MultiWriter(&res.Stdout, res.DebugData)
-		cmd.Stderr = io.MultiWriter(&res.Stderr, res.DebugData)
-	} else {
-		cmd.Stdout = &res.Stdout
-		cmd.Stderr = &res.Stderr
+	if c.pty != nil {
+		return c.executePTY(ctx, cmd, res)
 	}
+	cmd.Stdout, cmd.Stderr = c.outputs(res)
 	if c.stdin != nil {
 		cmd.Stdin = c.stdin
 	}
@@ -74,19 +124,53 @@ func (c *Command) Execute(ctx context.Context) (*types.Result, error) {
 	if err := cmd.Start(); err != nil {
 		// this error indicates that command did not start at all (e.g. binary not found)
 		// or something similar
-		return res, errkit.WithMessagef(err, "failed to start command got: %v", res.Stderr.String())
+		return res, errkit.WithMessagef(types.StartError(err), "failed to start command got: %v", res.Stderr.String())
 	}
 
 	if err := cmd.Wait(); err != nil {
 		if execErr, ok := err.(*exec.ExitError); ok {
 			res.SetExitError(execErr)
 		}
-		// this error indicates that command started but exited with non-zero exit code
-		return res, errkit.WithMessagef(err, "failed to exec command got: %v", res.Stderr.String())
+		// this error indicates that command started but exited with non-zero exit code,
+		// was killed or timed out
+		return res, errkit.WithMessagef(types.WaitError(ctx, err), "failed to exec command got: %v", res.Stderr.String())
 	}
 	return res, nil
 }
 
+// outputs returns the writers of the stdout and stderr of res,
+// including the debug data and transcript when enabled
+func (c *Command) outputs(res *types.Result) (stdout, stderr io.Writer) {
+	stdouts, stderrs := []io.Writer{&res.Stdout}, []io.Writer{&res.Stderr}
+	if c.debugMode {
+		res.DebugData = &bytes.Buffer{}
+		stdouts, stderrs = append(stdouts, res.DebugData), append(stderrs, res.DebugData)
+	}
+	if c.transcript {
+		res.Transcript = types.NewTranscript()
+		stdouts = append(stdouts, res.Transcript.Writer(types.StreamStdout))
+		stderrs = append(stderrs, res.Transcript.Writer(types.StreamStderr))
+	}
+	if len(stdouts) == 1 {
+		// nothing else is recorded
+		return stdouts[0], stderrs[0]
+	}
+	return io.MultiWriter(stdouts...), io.MultiWriter(stderrs...)
+}
+
+// setEnv sets the environment of cmd
+func (c *Command) setEnv(cmd *exec.Cmd) {
+	if c.cleanEnv {
+		cmd.Env = append([]string{}, c.Env...)
+		return
+	}
+	if len(c.Env) > 0 {
+		// by default we allow existing environment variables to be inherited
+		cmd.Env = append(cmd.Environ(), c.Env...)
+	}
+}
+
 // Extra Notes:
 // go before 1.21 did not follow symlinks when executing binaries and python installed from ms store creates a symlink
-// this is fixed https://github.com/golang/go/issues/42919 but just in case a workaround is to execute using low level api i.e os.startprocess
+// this is fixed https://github.com/golang/go/issues/42919 but just in case binaries found through a symlink are
+// executed using low level api i.e os.startprocess (see startprocess.go)
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package cmdexec
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteTranscript(t *testing.T) {
+	for _, startProcess := range []bool{false, true} {
+		// sleeps let the output of every stream be read before the next one is written
+		cmd, err := NewCommand("sh", "-c", "echo first; sleep 0.1; echo second >&2; sleep 0.1; echo third")
+		require.Nil(t, err)
+		cmd.SetPreferStartProcess(startProcess)
+		cmd.SetTranscript(true)
+		res, err := cmd.Execute(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, "first\nthird\n", res.Stdout.String())
+
+		chunks := res.Transcript.Chunks()
+		require.Len(t, chunks, 3)
+		require.Equal(t, []types.Stream{types.StreamStdout, types.StreamStderr, types.StreamStdout},
+			[]types.Stream{chunks[0].Stream, chunks[1].Stream, chunks[2].Stream})
+		require.Less(t, chunks[0].Elapsed, chunks[1].Elapsed)
+		require.Less(t, chunks[1].Elapsed, chunks[2].Elapsed)
+		require.Regexp(t, regexp.MustCompile(`^\[ +\d+\.\d{3}s\] stdout \| first\n\[ +\d+\.\d{3}s\] stderr \| second\n\[ +\d+\.\d{3}s\] stdout \| third\n$`), res.Transcript.String())
+	}
+
+	cmd, err := NewCommand("sh", "-c", "echo out")
+	require.Nil(t, err)
+	res, err := cmd.Execute(context.Background())
+	require.Nil(t, err)
+	require.Nil(t, res.Transcript)
+}
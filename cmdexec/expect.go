@@ -0,0 +1,143 @@
+package cmdexec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// DefaultExpectTimeout is used by expect steps without a timeout
+const DefaultExpectTimeout = 10 * time.Second
+
+var (
+	// ErrExpectTimeout is returned when the expected pattern is not seen in time
+	ErrExpectTimeout = errors.New("timeout waiting for expected output")
+	// ErrExpectEOF is returned when the output ends before the expected pattern is seen
+	ErrExpectEOF = errors.New("output closed before expected output")
+)
+
+// ExpectStep waits for Pattern in the output and then sends Send as input
+type ExpectStep struct {
+	Pattern *regexp.Regexp
+	Send    string
+	// Timeout for the pattern to appear (defaults to DefaultExpectTimeout)
+	Timeout time.Duration
+}
+
+// Expecter implements expect-style scripting over an output stream and an input writer.
+// Output is consumed in background as soon as the Expecter is created
+type Expecter struct {
+	w io.Writer
+
+	mu  sync.Mutex
+	buf []byte // output not yet matched
+	// released stops buffering output once no more steps are pending
+	released bool
+	notify   chan struct{}
+	err      error // set when the reader is done
+	done     chan struct{}
+}
+
+// NewExpecter creates an expecter reading output from r and writing input to w
+func NewExpecter(r io.Reader, w io.Writer) *Expecter {
+	e := &Expecter{
+		w:      w,
+		notify: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go e.read(r)
+	return e
+}
+
+func (e *Expecter) read(r io.Reader) {
+	defer close(e.done)
+	chunk := make([]byte, 4096)
+	for {
+		n, err := r.Read(chunk)
+		e.mu.Lock()
+		if n > 0 && !e.released {
+			e.buf = append(e.buf, chunk[:n]...)
+		}
+		if err != nil {
+			e.err = err
+		}
+		close(e.notify)
+		e.notify = make(chan struct{})
+		e.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Expect waits until pattern matches the output received since the previous match
+// and returns the matched text
+func (e *Expecter) Expect(pattern *regexp.Regexp, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		timeout = DefaultExpectTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		e.mu.Lock()
+		if loc := pattern.FindIndex(e.buf); loc != nil {
+			match := string(e.buf[loc[0]:loc[1]])
+			e.buf = append(e.buf[:0], e.buf[loc[1]:]...)
+			e.mu.Unlock()
+			return match, nil
+		}
+		if e.err != nil {
+			e.mu.Unlock()
+			return "", ErrExpectEOF
+		}
+		notify := e.notify
+		e.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return "", ErrExpectTimeout
+		}
+	}
+}
+
+// release drops the unmatched output and stops buffering further output,
+// no output can be expected afterwards
+func (e *Expecter) release() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.released = true
+	e.buf = nil
+}
+
+// Send writes input
+func (e *Expecter) Send(input string) error {
+	_, err := io.WriteString(e.w, input)
+	return err
+}
+
+// Run executes the steps in order, stopping at the first failure
+func (e *Expecter) Run(steps ...ExpectStep) error {
+	for _, step := range steps {
+		if step.Pattern != nil {
+			if _, err := e.Expect(step.Pattern, step.Timeout); err != nil {
+				return fmt.Errorf("%w: %s", err, step.Pattern)
+			}
+		}
+		if step.Send != "" {
+			if err := e.Send(step.Send); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Done is closed once the output stream is fully consumed
+func (e *Expecter) Done() <-chan struct{} {
+	return e.done
+}
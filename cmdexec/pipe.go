@@ -0,0 +1,68 @@
+package cmdexec
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// outputPipe is an in-memory pipe with an unbounded buffer so that
+// writes from the process never block on a slow (or absent) reader
+type outputPipe struct {
+	mu           sync.Mutex
+	cond         *sync.Cond
+	buf          bytes.Buffer
+	writerClosed bool
+	readerClosed bool
+}
+
+func newOutputPipe() *outputPipe {
+	p := &outputPipe{}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// Write appends data to the pipe buffer (data is discarded if the reader is closed)
+func (p *outputPipe) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.readerClosed {
+		p.buf.Write(data)
+		p.cond.Broadcast()
+	}
+	return len(data), nil
+}
+
+// Read blocks until data is available or the writer side is closed
+func (p *outputPipe) Read(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.buf.Len() == 0 && !p.writerClosed && !p.readerClosed {
+		p.cond.Wait()
+	}
+	if p.readerClosed {
+		return 0, io.ErrClosedPipe
+	}
+	if p.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return p.buf.Read(data)
+}
+
+// Close closes the reader side and discards buffered data
+func (p *outputPipe) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readerClosed = true
+	p.buf.Reset()
+	p.cond.Broadcast()
+	return nil
+}
+
+// closeWriter marks the end of the stream
+func (p *outputPipe) closeWriter() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.writerClosed = true
+	p.cond.Broadcast()
+}
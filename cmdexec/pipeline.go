@@ -0,0 +1,147 @@
+package cmdexec
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/projectdiscovery/gozero/internal/telemetry"
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/projectdiscovery/utils/errkit"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Pipeline runs commands connected with os pipes, the stdout of every
+// command is the stdin of the next one (like `a | b | c` without a shell)
+type Pipeline struct {
+	commands []*Command
+}
+
+// PipelineResult is the result of a pipeline execution
+type PipelineResult struct {
+	// Result combines the stages: stdout of the last stage, stderr of every
+	// stage in order and the exit status of the last failing stage (pipefail)
+	types.Result
+	// Stages are the results of every command. Stdout is only captured for
+	// the last stage, the others write to the next stage
+	Stages []*types.Result
+}
+
+// NewPipeline returns a pipeline of commands. The stdin set on the first
+// command is the stdin of the pipeline, the stdin of the others is ignored
+func NewPipeline(commands ...*Command) *Pipeline {
+	return &Pipeline{commands: commands}
+}
+
+// Execute runs every command of the pipeline and waits for all of them.
+// Canceling ctx kills every stage. Like pipefail, the pipeline fails with
+// the error of the last stage which failed (according to its success criteria),
+// including upstream stages killed by SIGPIPE when a downstream stage exits early
+func (p *Pipeline) Execute(ctx context.Context) (res *PipelineResult, err error) {
+	if len(p.commands) == 0 {
+		return nil, errkit.New("empty pipeline")
+	}
+	ctx, span := telemetry.Tracer(p.commands[0].tracerProvider).Start(ctx, "cmdexec.Pipeline.Execute",
+		trace.WithAttributes(telemetry.AttrEngine.String(p.commands[0].Binary)),
+	)
+	defer func() {
+		if res == nil {
+			telemetry.EndErr(span, err)
+			return
+		}
+		telemetry.End(span, &res.Result, err)
+	}()
+	// stages which started are killed when another one fails to start
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	res = &PipelineResult{}
+	cmds := make([]*exec.Cmd, len(p.commands))
+	names := make([]string, len(p.commands))
+	for i, c := range p.commands {
+		if c.pty != nil {
+			return nil, errkit.New("pty mode is not supported in pipelines")
+		}
+		cmd := exec.CommandContext(ctx, c.Binary, c.Args...)
+		c.setEnv(cmd)
+		if err := c.setAttr(cmd); err != nil {
+			return nil, types.StartError(err)
+		}
+		stage := &types.Result{Command: cmd.String()}
+		cmd.Stderr = &stage.Stderr
+		cmds[i], names[i] = cmd, stage.Command
+		res.Stages = append(res.Stages, stage)
+	}
+	res.Command = strings.Join(names, " | ")
+	last := len(cmds) - 1
+	if p.commands[0].stdin != nil {
+		cmds[0].Stdin = p.commands[0].stdin
+	}
+	cmds[last].Stdout = &res.Stages[last].Stdout
+
+	var pipes []*os.File
+	closePipes := func() {
+		for _, f := range pipes {
+			_ = f.Close()
+		}
+		pipes = nil
+	}
+	defer closePipes()
+	for i := 0; i < last; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return res, types.StartError(err)
+		}
+		pipes = append(pipes, r, w)
+		cmds[i].Stdout = w
+		cmds[i+1].Stdin = r
+	}
+
+	started := 0
+	var startErr error
+	for i, cmd := range cmds {
+		if err := cmd.Start(); err != nil {
+			startErr = errkit.WithMessagef(types.StartError(err), "failed to start pipeline stage %d", i)
+			cancel()
+			break
+		}
+		started++
+	}
+	// the stages hold their own copies of the pipe ends, closing ours lets
+	// readers see EOF and writers get EPIPE when a stage exits
+	closePipes()
+
+	stageErrs := make([]error, len(cmds))
+	for i := 0; i < started; i++ {
+		stage := res.Stages[i]
+		waitErr := cmds[i].Wait()
+		if execErr, ok := waitErr.(*exec.ExitError); ok {
+			stage.SetExitError(execErr)
+		}
+		if waitErr != nil {
+			waitErr = errkit.WithMessagef(types.WaitError(ctx, waitErr), "pipeline stage %d failed got: %v", i, stage.Stderr.String())
+		}
+		stageErrs[i] = p.commands[i].success.Check(stage, waitErr)
+	}
+
+	res.Stdout.Write(res.Stages[last].Stdout.Bytes())
+	for _, stage := range res.Stages {
+		res.Stderr.Write(stage.Stderr.Bytes())
+	}
+	if startErr != nil {
+		return res, startErr
+	}
+	for i := last; i >= 0; i-- {
+		if stageErrs[i] == nil {
+			continue
+		}
+		if exitErr := res.Stages[i].GetExitError(); exitErr != nil {
+			res.SetExitError(exitErr)
+		} else {
+			res.SetExitCode(res.Stages[i].GetExitCode())
+		}
+		return res, stageErrs[i]
+	}
+	return res, nil
+}
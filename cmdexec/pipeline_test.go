@@ -0,0 +1,90 @@
+//go:build !windows
+
+package cmdexec
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/stretchr/testify/require"
+)
+
+func newPipeline(t *testing.T, commands ...[]string) *Pipeline {
+	t.Helper()
+	var cmds []*Command
+	for _, c := range commands {
+		cmd, err := NewCommand(c[0], c[1:]...)
+		require.Nil(t, err)
+		cmds = append(cmds, cmd)
+	}
+	return NewPipeline(cmds...)
+}
+
+func TestPipeline(t *testing.T) {
+	p := newPipeline(t,
+		[]string{"sh", "-c", `printf 'b\na\nb\n'; echo first >&2`},
+		[]string{"sort"},
+		[]string{"uniq", "-c"},
+	)
+	res, err := p.Execute(context.Background())
+	require.Nil(t, err)
+	// uniq pads the counts
+	require.Equal(t, []string{"1", "a", "2", "b"}, strings.Fields(res.Stdout.String()))
+	require.Equal(t, "first\n", res.Stderr.String())
+	require.Equal(t, "first\n", res.Stages[0].Stderr.String())
+	require.Len(t, res.Stages, 3)
+	require.Equal(t, 0, res.GetExitCode())
+	require.Contains(t, res.Command, " | ")
+
+	// stdin of the first command is the stdin of the pipeline
+	p = newPipeline(t, []string{"cat"}, []string{"tr", "a-z", "A-Z"})
+	p.commands[0].SetStdin(strings.NewReader("gozero"))
+	res, err = p.Execute(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, "GOZERO", res.Stdout.String())
+}
+
+func TestPipelineFail(t *testing.T) {
+	// the last failing stage is reported
+	p := newPipeline(t,
+		[]string{"sh", "-c", "echo out; exit 3"},
+		[]string{"sh", "-c", "cat; echo failed >&2; exit 4"},
+		[]string{"cat"},
+	)
+	res, err := p.Execute(context.Background())
+	require.ErrorIs(t, err, types.ErrNonZeroExit)
+	require.Equal(t, 4, res.GetExitCode())
+	require.Equal(t, []int{3, 4, 0}, []int{res.Stages[0].GetExitCode(), res.Stages[1].GetExitCode(), res.Stages[2].GetExitCode()})
+	require.Equal(t, "out\n", res.Stdout.String())
+	require.Equal(t, "failed\n", res.Stderr.String())
+
+	// success criteria apply per stage
+	p = newPipeline(t, []string{"sh", "-c", "echo out; exit 3"}, []string{"cat"})
+	p.commands[0].SetSuccessCriteria(&types.SuccessCriteria{ExitCodes: []int{0, 3}})
+	res, err = p.Execute(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, 0, res.GetExitCode())
+	require.Equal(t, 3, res.Stages[0].GetExitCode())
+}
+
+func TestPipelineCancel(t *testing.T) {
+	p := newPipeline(t, []string{"sleep", "10"}, []string{"cat"})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := p.Execute(ctx)
+	require.ErrorIs(t, err, types.ErrTimeout)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	// started stages are killed when a stage fails to start
+	p = newPipeline(t, []string{"sleep", "10"}, []string{"cat"})
+	p.commands[1].Binary = filepath.Join(t.TempDir(), "missing")
+	start = time.Now()
+	_, err = p.Execute(context.Background())
+	require.ErrorIs(t, err, types.ErrStartFailed)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,183 @@
+package cmdexec
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/projectdiscovery/gozero/internal/telemetry"
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/projectdiscovery/utils/errkit"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Process is a handle to a command started with Command.Start.
+// All methods are safe for concurrent use
+type Process struct {
+	ctx     context.Context
+	success *types.SuccessCriteria
+	cmd     *exec.Cmd
+	spawned *spawned // set instead of cmd for the os.StartProcess executor
+	res     *types.Result
+	stdin   io.WriteCloser
+	stdout  *outputPipe
+	stderr  *outputPipe
+	span    trace.Span
+
+	waitErr error
+	done    chan struct{}
+}
+
+// Start starts the command without waiting for it to exit.
+// Unless a stdin reader was set with SetStdin, stdin is connected to a pipe
+// available through Process.Stdin. Output is captured in the Result returned
+// by Wait and is also readable incrementally through Process.Stdout and Process.Stderr.
+// PTY mode is not supported with Start. Like Execute, the os.StartProcess
+// executor is used when preferred or when the binary is a symlink
+func (c *Command) Start(ctx context.Context) (*Process, error) {
+	if c.pty != nil {
+		return nil, errkit.New("pty mode is not supported with start")
+	}
+	ctx, span := telemetry.Tracer(c.tracerProvider).Start(ctx, "cmdexec.Command.Start",
+		trace.WithAttributes(telemetry.AttrEngine.String(c.Binary)),
+	)
+	if c.useStartProcess() {
+		return c.startProcess(ctx, span)
+	}
+
+	cmd := exec.CommandContext(ctx, c.Binary, c.Args...)
+	c.setEnv(cmd)
+	if err := c.setAttr(cmd); err != nil {
+		telemetry.EndErr(span, err)
+		return nil, types.StartError(err)
+	}
+	p := &Process{
+		ctx:     ctx,
+		success: c.success,
+		cmd:     cmd,
+		res:     &types.Result{Command: cmd.String()},
+		stdout:  newOutputPipe(),
+		stderr:  newOutputPipe(),
+		span:    span,
+		done:    make(chan struct{}),
+	}
+	stdout, stderr := c.outputs(p.res)
+	cmd.Stdout = io.MultiWriter(stdout, p.stdout)
+	cmd.Stderr = io.MultiWriter(stderr, p.stderr)
+	if c.stdin != nil {
+		cmd.Stdin = c.stdin
+	} else {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			telemetry.EndErr(span, err)
+			return nil, err
+		}
+		p.stdin = stdin
+	}
+
+	if err := cmd.Start(); err != nil {
+		telemetry.EndErr(span, err)
+		return nil, errkit.WithMessage(types.StartError(err), "failed to start command")
+	}
+	go p.wait()
+	return p, nil
+}
+
+// startProcess starts the command with the os.StartProcess executor
+func (c *Command) startProcess(ctx context.Context, span trace.Span) (*Process, error) {
+	p := &Process{
+		ctx:     ctx,
+		success: c.success,
+		res:     &types.Result{Command: c.commandLine()},
+		stdout:  newOutputPipe(),
+		stderr:  newOutputPipe(),
+		span:    span,
+		done:    make(chan struct{}),
+	}
+	stdout, stderr := c.outputs(p.res)
+	sp, err := c.spawn(ctx, io.MultiWriter(stdout, p.stdout), io.MultiWriter(stderr, p.stderr), true)
+	if err != nil {
+		telemetry.EndErr(span, err)
+		return nil, errkit.WithMessage(err, "failed to start command")
+	}
+	p.spawned = sp
+	if sp.stdin != nil {
+		p.stdin = sp.stdin
+	}
+	go p.wait()
+	return p, nil
+}
+
+// process returns the started process
+func (p *Process) process() *os.Process {
+	if p.spawned != nil {
+		return p.spawned.proc
+	}
+	return p.cmd.Process
+}
+
+// Pid returns the process id
+func (p *Process) Pid() int {
+	return p.process().Pid
+}
+
+// Stdin returns the writable end of the process stdin (nil when a stdin reader was set).
+// Close it to signal EOF to the process
+func (p *Process) Stdin() io.WriteCloser {
+	return p.stdin
+}
+
+// Stdout returns a reader streaming the process stdout, which ends once the
+// process has exited. Output not read is buffered in memory (besides the
+// Result) until the reader is closed, close it when the output is not read
+func (p *Process) Stdout() io.ReadCloser {
+	return p.stdout
+}
+
+// Stderr returns a reader streaming the process stderr (see Stdout)
+func (p *Process) Stderr() io.ReadCloser {
+	return p.stderr
+}
+
+// Signal sends a signal to the process
+func (p *Process) Signal(sig os.Signal) error {
+	return p.process().Signal(sig)
+}
+
+// Kill kills the process
+func (p *Process) Kill() error {
+	return p.process().Kill()
+}
+
+// Done is closed once the process has exited and its result is complete
+func (p *Process) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait waits for the process to exit and returns its result.
+// It can be called multiple times and from multiple goroutines
+func (p *Process) Wait() (*types.Result, error) {
+	<-p.done
+	return p.res, p.waitErr
+}
+
+// wait waits for the process to exit, ends the output streams and completes
+// the result. It runs in background from the start of the process so that
+// output readers do not depend on Wait being called
+func (p *Process) wait() {
+	if p.spawned != nil {
+		state, err := p.spawned.wait(p.ctx)
+		p.waitErr = exitError(p.ctx, p.res, state, err)
+	} else if err := p.cmd.Wait(); err != nil {
+		if execErr, ok := err.(*exec.ExitError); ok {
+			p.res.SetExitError(execErr)
+		}
+		p.waitErr = errkit.WithMessagef(types.WaitError(p.ctx, err), "failed to exec command got: %v", p.res.Stderr.String())
+	}
+	p.stdout.closeWriter()
+	p.stderr.closeWriter()
+	p.waitErr = p.success.Check(p.res, p.waitErr)
+	telemetry.End(p.span, p.res, p.waitErr)
+	close(p.done)
+}
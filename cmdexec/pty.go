@@ -0,0 +1,103 @@
+package cmdexec
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+const (
+	// DefaultPTYRows is the default height of the pseudo-terminal
+	DefaultPTYRows = 24
+	// DefaultPTYCols is the default width of the pseudo-terminal
+	DefaultPTYCols = 80
+
+	// ptyDrainTimeout is the max time spent reading remaining output after exit
+	ptyDrainTimeout = time.Second
+	// eofChar is the terminal EOF character (ctrl-d)
+	eofChar = "\x04"
+)
+
+// ErrPTYUnsupported is returned when pseudo-terminals are not supported on the platform
+var ErrPTYUnsupported = pty.ErrUnsupported
+
+// PTYOptions configures pseudo-terminal execution
+type PTYOptions struct {
+	// Rows and Cols of the terminal window (defaults to 24x80)
+	Rows uint16
+	Cols uint16
+	// Expect steps are run against the terminal output once the command starts.
+	// When no steps are given stdin (if any) is forwarded followed by EOF
+	Expect []ExpectStep
+}
+
+// SetPTY enables execution in a pseudo-terminal. In PTY mode stdout and stderr
+// are merged and captured raw (including control sequences) in Result.Stdout
+func (c *Command) SetPTY(opts *PTYOptions) {
+	c.pty = opts
+}
+
+// executePTY starts cmd attached to a new pseudo-terminal and waits for it
+func (c *Command) executePTY(ctx context.Context, cmd *exec.Cmd, res *types.Result) (*types.Result, error) {
+	size := &pty.Winsize{Rows: c.pty.Rows, Cols: c.pty.Cols}
+	if size.Rows == 0 {
+		size.Rows = DefaultPTYRows
+	}
+	if size.Cols == 0 {
+		size.Cols = DefaultPTYCols
+	}
+
+	// stdout and stderr are merged by the terminal
+	output, _ := c.outputs(res)
+
+	ptmx, err := pty.StartWithSize(cmd, size)
+	if err != nil {
+		return res, errkit.WithMessage(types.StartError(err), "failed to start command in pty")
+	}
+	defer func() {
+		_ = ptmx.Close()
+	}()
+
+	expecter := NewExpecter(io.TeeReader(ptmx, output), ptmx)
+
+	var expectErr error
+	if len(c.pty.Expect) > 0 {
+		if expectErr = expecter.Run(c.pty.Expect...); expectErr != nil {
+			_ = cmd.Process.Kill()
+		}
+	}
+	// the remaining output is only kept in the result
+	expecter.release()
+	if len(c.pty.Expect) == 0 && c.stdin != nil {
+		go func() {
+			_, _ = io.Copy(ptmx, c.stdin)
+			_ = expecter.Send(eofChar)
+		}()
+	}
+
+	waitErr := cmd.Wait()
+
+	// the read side ends with EIO once the terminal is closed by all processes
+	select {
+	case <-expecter.Done():
+	case <-time.After(ptyDrainTimeout):
+		_ = ptmx.Close()
+		<-expecter.Done()
+	}
+
+	if expectErr != nil {
+		return res, errkit.WithMessagef(expectErr, "expect failed got: %v", res.Stdout.String())
+	}
+	if waitErr != nil {
+		if execErr, ok := waitErr.(*exec.ExitError); ok {
+			res.SetExitError(execErr)
+		}
+		return res, errkit.WithMessagef(types.WaitError(ctx, waitErr), "failed to exec command got: %v", res.Stdout.String())
+	}
+	return res, nil
+}
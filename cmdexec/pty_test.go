@@ -0,0 +1,60 @@
+//go:build !windows
+
+package cmdexec
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutePTY(t *testing.T) {
+	cmd, err := NewCommand("sh", "-c", `if [ -t 0 ] && [ -t 1 ]; then echo tty; fi; stty size`)
+	require.Nil(t, err)
+	cmd.SetPTY(&PTYOptions{Rows: 40, Cols: 120})
+	res, err := cmd.Execute(context.Background())
+	require.Nil(t, err)
+	out := strings.ReplaceAll(res.Stdout.String(), "\r", "")
+	require.Contains(t, out, "tty\n")
+	require.Contains(t, out, "40 120")
+}
+
+func TestExecutePTYExpect(t *testing.T) {
+	cmd, err := NewCommand("sh", "-c", `printf "name? "; read name; printf "pass? "; read pass; echo "hello $name"`)
+	require.Nil(t, err)
+	cmd.SetPTY(&PTYOptions{Expect: []ExpectStep{
+		{Pattern: regexp.MustCompile(`name\? `), Send: "gopher\n"},
+		{Pattern: regexp.MustCompile(`pass\? `), Send: "secret\n"},
+		{Pattern: regexp.MustCompile(`hello \w+`)},
+	}})
+	res, err := cmd.Execute(context.Background())
+	require.Nil(t, err)
+	require.Contains(t, res.Stdout.String(), "hello gopher")
+
+	cmd, err = NewCommand("sh", "-c", `printf "name? "; read name`)
+	require.Nil(t, err)
+	cmd.SetPTY(&PTYOptions{Expect: []ExpectStep{
+		{Pattern: regexp.MustCompile(`never`), Timeout: 200 * time.Millisecond},
+	}})
+	_, err = cmd.Execute(context.Background())
+	require.ErrorIs(t, err, ErrExpectTimeout)
+}
+
+func TestExpecterRelease(t *testing.T) {
+	r, w := io.Pipe()
+	e := NewExpecter(r, io.Discard)
+	e.release()
+	_, _ = io.WriteString(w, strings.Repeat("x", 1<<16))
+	_ = w.Close()
+	<-e.Done()
+
+	// output is not buffered once no more steps are pending
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	require.Empty(t, e.buf)
+}
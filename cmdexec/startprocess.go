@@ -0,0 +1,260 @@
+package cmdexec
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+// SetPreferStartProcess sets whether the command is executed with the low level
+// os.StartProcess api instead of os/exec. Commands whose binary is a symlink
+// always use it (e.g. python installed from the microsoft store)
+func (c *Command) SetPreferStartProcess(prefer bool) {
+	c.preferStartProcess = prefer
+}
+
+// useStartProcess reports whether Execute runs the command with os.StartProcess
+func (c *Command) useStartProcess() bool {
+	return c.pty == nil && (c.preferStartProcess || c.symlinked)
+}
+
+// isSymlink reports whether path is a symlink
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// executeStartProcess executes the command with os.StartProcess (see spawn)
+func (c *Command) executeStartProcess(ctx context.Context, res *types.Result) (*types.Result, error) {
+	stdout, stderr := c.outputs(res)
+	sp, err := c.spawn(ctx, stdout, stderr, false)
+	if err != nil {
+		return res, errkit.WithMessagef(err, "failed to start command got: %v", res.Stderr.String())
+	}
+	state, waitErr := sp.wait(ctx)
+	return res, exitError(ctx, res, state, waitErr)
+}
+
+// spawned is a process started with os.StartProcess
+type spawned struct {
+	proc   *os.Process
+	fds    *processFiles
+	stdin  *os.File // writable end of the stdin pipe, when requested
+	exited chan struct{}
+}
+
+// spawn starts the command with os.StartProcess, copying its output to stdout
+// and stderr. The symlink chain of the binary is resolved before starting it,
+// argv[0] is kept unresolved since some interpreters locate their environment
+// from it (e.g. python virtual environments). When stdinPipe is set and no stdin
+// reader was set, stdin is connected to a pipe whose writable end is returned.
+// The process is killed when ctx is done
+func (c *Command) spawn(ctx context.Context, stdout, stderr io.Writer, stdinPipe bool) (*spawned, error) {
+	binary, err := filepath.EvalSymlinks(c.Binary)
+	if err != nil {
+		return nil, errkit.WithMessagef(types.StartError(err), "failed to resolve %v", c.Binary)
+	}
+	sys, err := c.procAttr()
+	if err != nil {
+		return nil, types.StartError(err)
+	}
+
+	sp := &spawned{fds: &processFiles{}, exited: make(chan struct{})}
+	defer sp.fds.closeChild()
+	var stdinFile *os.File
+	if c.stdin == nil && stdinPipe {
+		stdinFile, sp.stdin, err = os.Pipe()
+		if err == nil {
+			sp.fds.child = append(sp.fds.child, stdinFile)
+		}
+	} else {
+		stdinFile, err = sp.fds.input(c.stdin)
+	}
+	if err != nil {
+		sp.fds.closeParent()
+		return nil, types.StartError(err)
+	}
+	stdoutFile, err := sp.fds.output(stdout)
+	if err != nil {
+		sp.close()
+		return nil, types.StartError(err)
+	}
+	stderrFile, err := sp.fds.output(stderr)
+	if err != nil {
+		sp.close()
+		return nil, types.StartError(err)
+	}
+
+	sp.proc, err = os.StartProcess(binary, append([]string{c.Binary}, c.Args...), &os.ProcAttr{
+		Dir:   c.dir,
+		Env:   c.environ(),
+		Files: append([]*os.File{stdinFile, stdoutFile, stderrFile}, c.extraFiles...),
+		Sys:   sys,
+	})
+	if err != nil {
+		sp.close()
+		return nil, types.StartError(err)
+	}
+	// the child holds its own copies of the descriptors, closing ours
+	// lets the copiers see EOF once the child exits
+	sp.fds.closeChild()
+	sp.fds.start()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = sp.proc.Kill()
+		case <-sp.exited:
+		}
+	}()
+	return sp, nil
+}
+
+// wait waits for the process to exit and for its output to be copied
+func (sp *spawned) wait(ctx context.Context) (*os.ProcessState, error) {
+	state, err := sp.proc.Wait()
+	close(sp.exited)
+	if ctx.Err() != nil {
+		// descendants may still hold the output pipes
+		sp.fds.closeParent()
+	}
+	sp.fds.wait()
+	if sp.stdin != nil {
+		// like os/exec, writes to the stdin of an exited process fail
+		_ = sp.stdin.Close()
+	}
+	return state, err
+}
+
+// close releases the descriptors of a process which failed to start
+func (sp *spawned) close() {
+	sp.fds.closeParent()
+	if sp.stdin != nil {
+		_ = sp.stdin.Close()
+	}
+}
+
+// exitError returns the error of a process which exited with state,
+// or failed to be waited for with waitErr, and records its exit in res
+func exitError(ctx context.Context, res *types.Result, state *os.ProcessState, waitErr error) error {
+	if waitErr != nil {
+		return errkit.WithMessagef(types.WaitError(ctx, waitErr), "failed to exec command got: %v", res.Stderr.String())
+	}
+	if !state.Success() {
+		exitErr := &exec.ExitError{ProcessState: state}
+		res.SetExitError(exitErr)
+		// this error indicates that command started but exited with non-zero exit code,
+		// was killed or timed out
+		return errkit.WithMessagef(types.WaitError(ctx, exitErr), "failed to exec command got: %v", res.Stderr.String())
+	}
+	return nil
+}
+
+// environ returns the environment of the process following the same rules as setEnv
+func (c *Command) environ() []string {
+	if c.cleanEnv {
+		// a nil environment would inherit the current one
+		return append([]string{}, c.Env...)
+	}
+	if len(c.Env) > 0 {
+		return append(os.Environ(), c.Env...)
+	}
+	return nil
+}
+
+// commandLine returns the command line like exec.Cmd.String
+func (c *Command) commandLine() string {
+	return strings.Join(append([]string{c.Binary}, c.Args...), " ")
+}
+
+// processFiles are the descriptors passed to a process started with
+// os.StartProcess and the goroutines copying data from and to them
+type processFiles struct {
+	child   []*os.File // ends inherited by the process
+	parent  []*os.File // ends read or written by the copiers
+	copiers []func()
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// input returns the stdin of the process. Files are passed as is, other
+// readers are copied through a pipe and no reader is replaced by the null device
+func (f *processFiles) input(r io.Reader) (*os.File, error) {
+	if file, ok := r.(*os.File); ok {
+		return file, nil
+	}
+	if r == nil {
+		file, err := os.Open(os.DevNull)
+		if err != nil {
+			return nil, err
+		}
+		f.child = append(f.child, file)
+		return file, nil
+	}
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	f.child = append(f.child, pr)
+	f.parent = append(f.parent, pw)
+	f.copiers = append(f.copiers, func() {
+		// the process may exit without reading its whole input
+		_, _ = io.Copy(pw, r)
+		_ = pw.Close()
+	})
+	return pr, nil
+}
+
+// output returns a pipe whose data is copied to w
+func (f *processFiles) output(w io.Writer) (*os.File, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	f.child = append(f.child, pw)
+	f.parent = append(f.parent, pr)
+	f.copiers = append(f.copiers, func() {
+		_, _ = io.Copy(w, pr)
+	})
+	return pw, nil
+}
+
+// start starts the copiers
+func (f *processFiles) start() {
+	for _, copier := range f.copiers {
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			copier()
+		}()
+	}
+}
+
+// wait waits for the copiers and releases the parent ends. Like os/exec,
+// writes to the stdin of an exited process fail instead of blocking
+func (f *processFiles) wait() {
+	f.wg.Wait()
+	f.closeParent()
+}
+
+func (f *processFiles) closeChild() {
+	for _, file := range f.child {
+		_ = file.Close()
+	}
+	f.child = nil
+}
+
+func (f *processFiles) closeParent() {
+	f.once.Do(func() {
+		for _, file := range f.parent {
+			_ = file.Close()
+		}
+	})
+}
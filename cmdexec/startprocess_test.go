@@ -0,0 +1,135 @@
+//go:build !windows
+
+package cmdexec
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteStartProcess(t *testing.T) {
+	sh, err := NewCommand("sh")
+	require.Nil(t, err)
+	resolved, err := filepath.EvalSymlinks(sh.Binary)
+	require.Nil(t, err)
+
+	// a symlink chain to sh is executed with os.StartProcess
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	require.Nil(t, os.Symlink(resolved, second))
+	require.Nil(t, os.Symlink(second, first))
+
+	cmd, err := NewCommand(first, "-c", `read line; echo "out $line $GOZERO_VAR"; echo err >&2; exit 3`)
+	require.Nil(t, err)
+	require.True(t, cmd.useStartProcess())
+	cmd.SetStdin(strings.NewReader("input\n"))
+	cmd.AddVars(types.Variable{Name: "GOZERO_VAR", Value: "value"})
+	res, err := cmd.Execute(context.Background())
+	require.ErrorIs(t, err, types.ErrNonZeroExit)
+	require.Equal(t, "out input value\n", res.Stdout.String())
+	require.Equal(t, "err\n", res.Stderr.String())
+	require.Equal(t, 3, res.GetExitCode())
+	require.NotNil(t, res.GetExitError())
+	require.Equal(t, first+" -c "+cmd.Args[1], res.Command)
+
+	// broken chains fail to start
+	require.Nil(t, os.Remove(second))
+	_, err = cmd.Execute(context.Background())
+	require.ErrorIs(t, err, types.ErrStartFailed)
+
+	// clean environments are not inherited
+	t.Setenv("GOZERO_INHERITED", "inherited")
+	cmd, err = NewCommand("sh", "-c", `echo "[$GOZERO_INHERITED]"`)
+	require.Nil(t, err)
+	cmd.SetPreferStartProcess(true)
+	cmd.SetCleanEnv(true)
+	res, err = cmd.Execute(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, "[]\n", res.Stdout.String())
+	require.Equal(t, 0, res.GetExitCode())
+}
+
+func TestExecuteStartProcessTimeout(t *testing.T) {
+	cmd, err := NewCommand("sh", "-c", "exec sleep 10")
+	require.Nil(t, err)
+	cmd.SetPreferStartProcess(true)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = cmd.Execute(ctx)
+	require.ErrorIs(t, err, types.ErrTimeout)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestStartStartProcess(t *testing.T) {
+	cmd, err := NewCommand("sh", "-c", `read line; echo "out $line"; echo err >&2; exit 3`)
+	require.Nil(t, err)
+	cmd.SetPreferStartProcess(true)
+	proc, err := cmd.Start(context.Background())
+	require.Nil(t, err)
+	require.NotNil(t, proc.spawned, "start must use the os.StartProcess executor")
+	require.Greater(t, proc.Pid(), 0)
+
+	streamed := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(proc.Stdout())
+		streamed <- data
+	}()
+	_, err = proc.Stdin().Write([]byte("input\n"))
+	require.Nil(t, err)
+	require.Nil(t, proc.Stdin().Close())
+
+	res, err := proc.Wait()
+	require.ErrorIs(t, err, types.ErrNonZeroExit)
+	require.Equal(t, "out input\n", string(<-streamed))
+	require.Equal(t, 3, res.GetExitCode())
+	require.Equal(t, "out input\n", res.Stdout.String())
+	require.Equal(t, "err\n", res.Stderr.String())
+
+	// canceled processes are killed
+	cmd, err = NewCommand("sleep", "10")
+	require.Nil(t, err)
+	cmd.SetPreferStartProcess(true)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	proc, err = cmd.Start(ctx)
+	require.Nil(t, err)
+	start := time.Now()
+	_, err = proc.Wait()
+	require.ErrorIs(t, err, types.ErrTimeout)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestStartReadBeforeWait(t *testing.T) {
+	for _, preferStartProcess := range []bool{false, true} {
+		cmd, err := NewCommand("echo", "hi")
+		require.Nil(t, err)
+		cmd.SetPreferStartProcess(preferStartProcess)
+		proc, err := cmd.Start(context.Background())
+		require.Nil(t, err)
+
+		// output streams end when the process exits, without calling Wait
+		streamed := make(chan []byte)
+		go func() {
+			data, _ := io.ReadAll(proc.Stdout())
+			streamed <- data
+		}()
+		select {
+		case data := <-streamed:
+			require.Equal(t, "hi\n", string(data))
+		case <-time.After(5 * time.Second):
+			t.Fatalf("stdout did not end before wait (prefer start process: %v)", preferStartProcess)
+		}
+		res, err := proc.Wait()
+		require.Nil(t, err)
+		require.Equal(t, "hi\n", res.Stdout.String())
+	}
+}
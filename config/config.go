@@ -0,0 +1,231 @@
+// config package loads named execution profiles from YAML or JSON files.
+//
+// A profile selects an engine (or language), its arguments, the environment
+// passed to evaluations, resource limits and the sandbox backend with its
+// settings. Values can reference environment variables as ${NAME} or
+// ${NAME:-default} ($$ is a literal $). Every profile can be turned into a
+// ready-to-use Executor.
+//
+//	profiles:
+//	  python:
+//	    language: python
+//	    preflight: true
+//	    env:
+//	      inherit: false
+//	      allow: [PATH]
+//	      set:
+//	        API_TOKEN: ${API_TOKEN}
+//	    limits:
+//	      timeout: 30s
+//	      memory: 256m
+//	      network: none
+//	    sandbox:
+//	      backend: docker
+//	      docker:
+//	        image: python:3-alpine
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Backends a profile can run on
+const (
+	BackendNone       = "none"
+	BackendDocker     = "docker"
+	BackendBubblewrap = "bwrap"
+	BackendSystemd    = "systemd"
+)
+
+// Config is the content of a configuration file
+type Config struct {
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// Profile is a named execution profile
+type Profile struct {
+	// Engines to try in order (mutually exclusive with Language)
+	Engines []string `yaml:"engines"`
+	// Language selects the engines (and default docker image) of a known language
+	Language string `yaml:"language"`
+	// Args are passed to the engine before the script
+	Args []string `yaml:"args"`
+	// Preflight checks the syntax of sources on the host before running them
+	Preflight bool      `yaml:"preflight"`
+	Env       EnvPolicy `yaml:"env"`
+	Limits    Limits    `yaml:"limits"`
+	Sandbox   Sandbox   `yaml:"sandbox"`
+}
+
+// EnvPolicy declares the environment passed to evaluations
+type EnvPolicy struct {
+	// Inherit passes the whole environment of the current process
+	// (defaults to true, only supported without sandbox)
+	Inherit *bool `yaml:"inherit"`
+	// Allow lists variables of the current process passed through
+	Allow []string `yaml:"allow"`
+	// Set are variables set for every evaluation
+	Set map[string]string `yaml:"set"`
+}
+
+// Limits are the resource limits of a profile
+type Limits struct {
+	Timeout Duration `yaml:"timeout"`
+	// Memory limit (e.g. 512m) for docker and systemd
+	Memory string `yaml:"memory"`
+	// CPUs limit (e.g. 0.5) for docker and systemd
+	CPUs string `yaml:"cpus"`
+	// Network none disables networking, docker also accepts a network mode
+	Network string `yaml:"network"`
+}
+
+// Sandbox is the backend of a profile with its settings
+type Sandbox struct {
+	// Backend is one of none (default), docker, bwrap or systemd
+	Backend    string              `yaml:"backend"`
+	Docker     *DockerSettings     `yaml:"docker"`
+	Bubblewrap *BubblewrapSettings `yaml:"bwrap"`
+	Systemd    *SystemdSettings    `yaml:"systemd"`
+}
+
+// DockerSettings configure the docker backend
+type DockerSettings struct {
+	Image      string `yaml:"image"`
+	WorkingDir string `yaml:"working_dir"`
+	User       string `yaml:"user"`
+	// Keep keeps containers after execution
+	Keep bool `yaml:"keep"`
+}
+
+// BubblewrapSettings configure the bubblewrap backend
+type BubblewrapSettings struct {
+	TempDir        string `yaml:"temp_dir"`
+	UnsharePID     bool   `yaml:"unshare_pid"`
+	UnshareIPC     bool   `yaml:"unshare_ipc"`
+	UnshareUTS     bool   `yaml:"unshare_uts"`
+	UnshareUser    bool   `yaml:"unshare_user"`
+	UnshareCgroup  bool   `yaml:"unshare_cgroup"`
+	NewSession     bool   `yaml:"new_session"`
+	UID            int    `yaml:"uid"`
+	GID            int    `yaml:"gid"`
+	SeccompFile    string `yaml:"seccomp_file"`
+	HostFilesystem bool   `yaml:"host_filesystem"`
+	ReadOnlyBinds  []Bind `yaml:"read_only_binds"`
+}
+
+// Bind is a bind mount of a host path in the sandbox
+type Bind struct {
+	Host    string `yaml:"host"`
+	Sandbox string `yaml:"sandbox"`
+}
+
+// SystemdSettings configure the systemd backend
+type SystemdSettings struct {
+	Properties []Property `yaml:"properties"`
+}
+
+// Property is a systemd unit property (e.g. PrivateTmp=yes or
+// ReadOnlyDirectories=/etc,/home). Exactly one of Value and Values is set
+type Property struct {
+	Name   string   `yaml:"name"`
+	Value  string   `yaml:"value"`
+	Values []string `yaml:"values"`
+}
+
+// Duration is a time.Duration written as a string (e.g. 30s)
+type Duration time.Duration
+
+// UnmarshalYAML parses the duration from a string
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	v, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", node.Value)
+	}
+	if v < 0 {
+		return fmt.Errorf("negative duration %q", node.Value)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Option configures the loading of a configuration
+type Option func(*loader)
+
+// WithLookupEnv sets the function resolving ${NAME} references
+// (defaults to os.LookupEnv)
+func WithLookupEnv(lookup func(name string) (string, bool)) Option {
+	return func(l *loader) {
+		l.lookupEnv = lookup
+	}
+}
+
+type loader struct {
+	filename  string
+	lookupEnv func(name string) (string, bool)
+}
+
+// Load reads and validates the configuration file at path.
+// Validation failures are returned as Errors
+func Load(path string, opts ...Option) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, path, opts...)
+}
+
+// Parse parses and validates a YAML or JSON configuration.
+// filename is only used in error messages
+func Parse(data []byte, filename string, opts ...Option) (*Config, error) {
+	l := &loader{filename: filename, lookupEnv: os.LookupEnv}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, l.syntaxError(err)
+	}
+	v := &validator{loader: l, nodes: map[string]*yaml.Node{}}
+	if len(root.Content) == 0 {
+		v.errorf(&root, "", "empty configuration")
+		return nil, v.errs
+	}
+	doc := root.Content[0]
+	v.interpolate(doc, "")
+	v.schema(doc, "", configType)
+	if len(v.errs) > 0 {
+		return nil, v.errs
+	}
+
+	config := &Config{}
+	if err := doc.Decode(config); err != nil {
+		return nil, l.syntaxError(err)
+	}
+	v.semantics(config)
+	if len(v.errs) > 0 {
+		return nil, v.errs
+	}
+	return config, nil
+}
+
+// LoadExecutors loads the configuration file at path and returns the executors of all its profiles
+func LoadExecutors(path string, opts ...Option) (map[string]Executor, error) {
+	config, err := Load(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	executors := make(map[string]Executor, len(config.Profiles))
+	for name := range config.Profiles {
+		executor, err := config.Executor(name)
+		if err != nil {
+			return nil, err
+		}
+		executors[name] = executor
+	}
+	return executors, nil
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/gozero"
+	"github.com/projectdiscovery/gozero/preflight"
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/stretchr/testify/require"
+)
+
+func lookup(env map[string]string) Option {
+	return WithLookupEnv(func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+}
+
+func TestParse(t *testing.T) {
+	data := `
+profiles:
+  python:
+    language: python
+    args: ["-u"]
+    env:
+      inherit: false
+      allow: [PATH]
+      set:
+        TOKEN: ${TOKEN}
+        REGION: ${REGION:-eu}
+        PRICE: $$5
+    limits:
+      timeout: 30s
+      memory: ${MEMORY}
+      network: none
+    sandbox:
+      backend: docker
+      docker:
+        image: python:3-alpine
+  shell:
+    engines: [bash, sh]
+`
+	config, err := Parse([]byte(data), "gozero.yaml", lookup(map[string]string{"TOKEN": "secret", "MEMORY": "256m"}))
+	require.NoError(t, err)
+	require.Len(t, config.Profiles, 2)
+
+	python := config.Profiles["python"]
+	require.Equal(t, []string{"-u"}, python.Args)
+	require.False(t, *python.Env.Inherit)
+	require.Equal(t, map[string]string{"TOKEN": "secret", "REGION": "eu", "PRICE": "$5"}, python.Env.Set)
+	require.Equal(t, Duration(30*time.Second), python.Limits.Timeout)
+	require.Equal(t, "256m", python.Limits.Memory)
+	require.Equal(t, BackendDocker, python.Sandbox.Backend)
+	require.Equal(t, "python:3-alpine", python.Sandbox.Docker.Image)
+	require.Equal(t, []string{"bash", "sh"}, config.Profiles["shell"].Engines)
+}
+
+func TestParseJSON(t *testing.T) {
+	data := `{"profiles": {"shell": {"engines": ["sh"], "limits": {"timeout": "1s"}}}}`
+	config, err := Parse([]byte(data), "gozero.json")
+	require.NoError(t, err)
+	require.Equal(t, Duration(time.Second), config.Profiles["shell"].Limits.Timeout)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		path   string
+		line   int
+		column int
+		msg    string
+	}{
+		{
+			name: "unknown field",
+			data: "profiles:\n  shell:\n    engines: [sh]\n    engine: sh\n",
+			path: "profiles.shell.engine", line: 4, column: 5, msg: `unknown field "engine"`,
+		},
+		{
+			name: "wrong type",
+			data: "profiles:\n  shell:\n    engines: [sh]\n    env:\n      inherit: maybe\n",
+			path: "profiles.shell.env.inherit", line: 5, column: 16, msg: "expected a boolean",
+		},
+		{
+			name: "unset variable",
+			data: "profiles:\n  shell:\n    engines: [sh]\n    env:\n      set:\n        TOKEN: ${MISSING}\n",
+			path: "profiles.shell.env.set.TOKEN", line: 6, column: 16, msg: "MISSING is not set",
+		},
+		{
+			name: "unknown backend",
+			data: "profiles:\n  shell:\n    engines: [sh]\n    sandbox:\n      backend: jail\n",
+			path: "profiles.shell.sandbox.backend", line: 5, column: 16, msg: `unknown backend "jail"`,
+		},
+		{
+			name: "engines and language",
+			data: "profiles:\n  shell:\n    engines: [sh]\n    language: sh\n",
+			path: "profiles.shell.language", line: 4, column: 15, msg: "mutually exclusive",
+		},
+		{
+			name: "settings of another backend",
+			data: "profiles:\n  shell:\n    engines: [sh]\n    sandbox:\n      backend: systemd\n      docker:\n        image: alpine\n",
+			path: "profiles.shell.sandbox.docker", line: 7, column: 9, msg: "docker settings require backend docker",
+		},
+		{
+			name: "limit without sandbox",
+			data: "profiles:\n  shell:\n    engines: [sh]\n    limits:\n      memory: 1g\n",
+			path: "profiles.shell.limits.memory", line: 5, column: 15, msg: "requires a sandbox backend",
+		},
+		{
+			name: "invalid duration",
+			data: "profiles:\n  shell:\n    engines: [sh]\n    limits:\n      timeout: soon\n",
+			path: "profiles.shell.limits.timeout", line: 5, column: 16, msg: `invalid duration "soon"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data), "gozero.yaml", lookup(nil))
+			var errs Errors
+			require.ErrorAs(t, err, &errs)
+			require.Len(t, errs, 1, err.Error())
+			require.Equal(t, "gozero.yaml", errs[0].File)
+			require.Equal(t, tt.path, errs[0].Path)
+			require.Equal(t, tt.line, errs[0].Line)
+			if tt.column > 0 {
+				require.Equal(t, tt.column, errs[0].Column)
+			}
+			require.Contains(t, errs[0].Message, tt.msg)
+		})
+	}
+}
+
+func TestLoadExecutors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	t.Setenv("GOZERO_HOST_VAR", "host")
+	t.Setenv("GOZERO_ALLOWED_VAR", "allowed")
+	path := filepath.Join(t.TempDir(), "gozero.yaml")
+	data := `
+profiles:
+  inherit:
+    engines: [sh]
+  clean:
+    engines: [sh]
+    env:
+      inherit: false
+      allow: [GOZERO_ALLOWED_VAR]
+      set:
+        GOZERO_SET_VAR: set
+  slow:
+    engines: [sh]
+    limits:
+      timeout: 100ms
+  checked:
+    engines: [sh]
+    preflight: true
+`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	executors, err := LoadExecutors(path)
+	require.NoError(t, err)
+	require.Len(t, executors, 4)
+
+	script := `echo "$GOZERO_HOST_VAR|$GOZERO_ALLOWED_VAR|$GOZERO_SET_VAR"`
+	eval := func(name, src string) (*types.Result, error) {
+		source, err := gozero.NewSourceWithString(src, "", "")
+		require.NoError(t, err)
+		defer func() {
+			_ = source.Cleanup()
+		}()
+		return executors[name].Eval(context.Background(), source, nil)
+	}
+
+	res, err := eval("inherit", script)
+	require.NoError(t, err)
+	require.Equal(t, "host|allowed|\n", res.Stdout.String())
+
+	res, err = eval("clean", script)
+	require.NoError(t, err)
+	require.Equal(t, "|allowed|set\n", res.Stdout.String())
+
+	_, err = eval("slow", "exec sleep 5")
+	require.True(t, errors.Is(err, types.ErrTimeout), err)
+
+	_, err = eval("checked", "if true; then echo 1")
+	require.ErrorIs(t, err, preflight.ErrSyntax)
+}
+
+func TestContainerInterpreter(t *testing.T) {
+	p := &Profile{Engines: []string{"python3"}, Args: []string{"-W", "ignore::Warning; rm -rf /", "it's"}}
+	// args are single shell words, never interpreted by the shell of the container
+	require.Equal(t, `python3 '-W' 'ignore::Warning; rm -rf /' 'it'\''s'`, p.containerInterpreter())
+}
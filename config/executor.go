@@ -0,0 +1,216 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/gozero"
+	"github.com/projectdiscovery/gozero/internal/runner"
+	"github.com/projectdiscovery/gozero/preflight"
+	"github.com/projectdiscovery/gozero/sandbox"
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// Executor evaluates sources with the settings of a profile
+type Executor interface {
+	// Eval evaluates src with input (optional) as stdin and returns the result.
+	// Variables of src and input are passed as environment variables, after
+	// the ones of the profile env policy
+	Eval(ctx context.Context, src, input *gozero.Source, args ...string) (*types.Result, error)
+}
+
+// Executor returns a ready-to-use executor for the named profile
+func (c *Config) Executor(name string) (Executor, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	executor, err := profile.Executor()
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	return executor, nil
+}
+
+// Executor returns a ready-to-use executor for the profile
+func (p *Profile) Executor() (Executor, error) {
+	switch p.Sandbox.backend() {
+	case BackendNone:
+		return newLocalExecutor(p)
+	case BackendDocker:
+		return &dockerExecutor{profile: p}, nil
+	case BackendBubblewrap:
+		return newBubblewrapExecutor(p)
+	case BackendSystemd:
+		return newSystemdExecutor(p)
+	default:
+		return nil, fmt.Errorf("unknown backend %q", p.Sandbox.Backend)
+	}
+}
+
+// engines returns the engines to try in order
+func (p *Profile) engines() []string {
+	if len(p.Engines) > 0 {
+		return p.Engines
+	}
+	return runner.Languages[p.Language].Engines
+}
+
+// variables returns the variables of the env policy. Allowed variables
+// are read from the environment of the current process on every call
+func (p *Profile) variables() []types.Variable {
+	var vars []types.Variable
+	for _, name := range p.Env.Allow {
+		if value, ok := os.LookupEnv(name); ok {
+			vars = append(vars, types.Variable{Name: name, Value: value})
+		}
+	}
+	names := make([]string, 0, len(p.Env.Set))
+	for name := range p.Env.Set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		vars = append(vars, types.Variable{Name: name, Value: p.Env.Set[name]})
+	}
+	return vars
+}
+
+// environment returns the variables of the profile, src and input as a map
+func (p *Profile) environment(src, input *gozero.Source) map[string]string {
+	env := map[string]string{}
+	groups := [][]types.Variable{p.variables(), src.Variables}
+	if input != nil {
+		groups = append(groups, input.Variables)
+	}
+	for _, vars := range groups {
+		for _, variable := range vars {
+			env[variable.Name] = variable.Value
+		}
+	}
+	return env
+}
+
+// withTimeout applies the timeout limit of the profile to ctx
+func (p *Profile) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.Limits.Timeout > 0 {
+		return context.WithTimeout(ctx, time.Duration(p.Limits.Timeout))
+	}
+	return context.WithCancel(ctx)
+}
+
+// preflight checks the syntax of src with the first engine of the profile
+// installed on the host, when enabled
+func (p *Profile) preflight(ctx context.Context, src *gozero.Source) error {
+	if !p.Preflight {
+		return nil
+	}
+	for _, engine := range p.engines() {
+		if path, err := exec.LookPath(engine); err == nil && preflight.Supported(path) {
+			return preflight.Validate(ctx, path, src.Filename)
+		}
+	}
+	return nil
+}
+
+// localExecutor evaluates sources with gozero without sandbox
+type localExecutor struct {
+	profile *Profile
+	gozero  *gozero.Gozero
+}
+
+func newLocalExecutor(p *Profile) (Executor, error) {
+	g, err := gozero.New(&gozero.Options{
+		Engines:   p.engines(),
+		Args:      p.Args,
+		Preflight: p.Preflight,
+		CleanEnv:  p.Env.Inherit != nil && !*p.Env.Inherit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &localExecutor{profile: p, gozero: g}, nil
+}
+
+func (e *localExecutor) Eval(ctx context.Context, src, input *gozero.Source, args ...string) (*types.Result, error) {
+	ctx, cancel := e.profile.withTimeout(ctx)
+	defer cancel()
+
+	in := gozero.Source{}
+	if input != nil {
+		in = *input
+	}
+	in.Variables = append(e.profile.variables(), in.Variables...)
+	return e.gozero.Eval(ctx, src, &in, args...)
+}
+
+// dockerExecutor evaluates sources in a new docker container
+type dockerExecutor struct {
+	profile *Profile
+}
+
+func (e *dockerExecutor) Eval(ctx context.Context, src, input *gozero.Source, args ...string) (*types.Result, error) {
+	if len(args) > 0 {
+		return nil, errors.New("script arguments are not supported by the docker backend")
+	}
+	if input != nil && input.File != nil {
+		return nil, errors.New("input is not supported by the docker backend")
+	}
+	content, err := src.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := e.profile.withTimeout(ctx)
+	defer cancel()
+	if err := e.profile.preflight(ctx, src); err != nil {
+		return nil, err
+	}
+
+	p := e.profile
+	config := &sandbox.DockerConfiguration{
+		Image:           runner.Languages[p.Language].Image,
+		WorkingDir:      "/tmp",
+		Environment:     p.environment(src, input),
+		Memory:          p.Limits.Memory,
+		CPULimit:        p.Limits.CPUs,
+		NetworkDisabled: p.Limits.Network == "none",
+		Remove:          true,
+	}
+	if p.Limits.Network != "none" {
+		config.NetworkMode = p.Limits.Network
+	}
+	if settings := p.Sandbox.Docker; settings != nil {
+		if settings.Image != "" {
+			config.Image = settings.Image
+		}
+		if settings.WorkingDir != "" {
+			config.WorkingDir = settings.WorkingDir
+		}
+		config.User = settings.User
+		config.Remove = !settings.Keep
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		config.Timeout = time.Until(deadline)
+	}
+	docker, err := sandbox.NewDockerSandbox(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return docker.RunSource(ctx, string(content), p.containerInterpreter())
+}
+
+// containerInterpreter returns the interpreter command of the profile, as run
+// by the shell of the container
+func (p *Profile) containerInterpreter() string {
+	parts := []string{runner.ContainerInterpreter(p.engines())}
+	for _, arg := range p.Args {
+		parts = append(parts, runner.ShellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,199 @@
+//go:build linux
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/projectdiscovery/gozero"
+	"github.com/projectdiscovery/gozero/internal/runner"
+	"github.com/projectdiscovery/gozero/sandbox"
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// bubblewrapExecutor evaluates sources in a bubblewrap sandbox
+type bubblewrapExecutor struct {
+	profile *Profile
+	engine  string
+	sandbox *sandbox.BubblewrapSandbox
+}
+
+func newBubblewrapExecutor(p *Profile) (Executor, error) {
+	// the host filesystem is visible in the sandbox, engines are looked up on the host
+	engine, err := runner.LookEngine(p.engines())
+	if err != nil {
+		return nil, err
+	}
+	config := &sandbox.BubblewrapConfiguration{HostFilesystem: true}
+	if s := p.Sandbox.Bubblewrap; s != nil {
+		config = &sandbox.BubblewrapConfiguration{
+			TempDir:        s.TempDir,
+			UnsharePID:     s.UnsharePID,
+			UnshareIPC:     s.UnshareIPC,
+			UnshareUTS:     s.UnshareUTS,
+			UnshareUser:    s.UnshareUser,
+			UnshareCgroup:  s.UnshareCgroup,
+			NewSession:     s.NewSession,
+			UID:            s.UID,
+			GID:            s.GID,
+			SeccompFile:    s.SeccompFile,
+			HostFilesystem: s.HostFilesystem,
+		}
+		for _, bind := range s.ReadOnlyBinds {
+			config.ReadOnlySystemBinds = append(config.ReadOnlySystemBinds, sandbox.BindMount{HostPath: bind.Host, SandboxPath: bind.Sandbox})
+		}
+	}
+	bwrap, err := sandbox.NewBubblewrapSandbox(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+	return &bubblewrapExecutor{profile: p, engine: engine, sandbox: bwrap}, nil
+}
+
+func (e *bubblewrapExecutor) Eval(ctx context.Context, src, input *gozero.Source, args ...string) (*types.Result, error) {
+	content, err := src.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	// only the script is made visible inside the sandbox
+	scriptDir, err := os.MkdirTemp("", "gozero-script-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(scriptDir)
+	}()
+	if err := os.WriteFile(filepath.Join(scriptDir, "script"), content, 0644); err != nil {
+		return nil, err
+	}
+
+	p := e.profile
+	cmdArgs := append(append(append([]string{}, p.Args...), "/src/script"), args...)
+	options := &sandbox.BubblewrapCommandOptions{
+		Command:      e.engine,
+		Engine:       e.engine,
+		Args:         cmdArgs,
+		CommandBinds: []sandbox.BindMount{{HostPath: scriptDir, SandboxPath: "/src"}},
+		Chdir:        "/src",
+		Environment:  p.environment(src, input),
+	}
+	if input != nil && input.File != nil {
+		stdin, err := io.ReadAll(input.File)
+		if err != nil {
+			return nil, err
+		}
+		options.Stdin = string(stdin)
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	if err := p.preflight(ctx, src); err != nil {
+		return nil, err
+	}
+	return e.sandbox.ExecuteWithOptions(ctx, options)
+}
+
+// systemdExecutor evaluates sources in a transient systemd unit
+type systemdExecutor struct {
+	profile *Profile
+	engine  string
+}
+
+func newSystemdExecutor(p *Profile) (Executor, error) {
+	engine, err := runner.LookEngine(p.engines())
+	if err != nil {
+		return nil, err
+	}
+	return &systemdExecutor{profile: p, engine: engine}, nil
+}
+
+func (e *systemdExecutor) Eval(ctx context.Context, src, input *gozero.Source, args ...string) (*types.Result, error) {
+	if input != nil && input.File != nil {
+		return nil, fmt.Errorf("input is not supported by the systemd backend")
+	}
+	scriptDir, script, err := stageSystemdScript(src)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(scriptDir)
+	}()
+	config, err := e.profile.systemdConfiguration(src, input)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := e.profile.withTimeout(ctx)
+	defer cancel()
+	if err := e.profile.preflight(ctx, src); err != nil {
+		return nil, err
+	}
+	systemd, err := sandbox.New(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	parts := append(append([]string{e.engine}, e.profile.Args...), script)
+	return systemd.RunArgs(ctx, append(parts, args...))
+}
+
+// stageSystemdScript copies src to a new directory in the user cache directory
+// and returns the directory and the path of the copy. Units may have a private
+// /tmp (PrivateTmp) hiding the host one, where sources are usually created
+func stageSystemdScript(src *gozero.Source) (string, string, error) {
+	content, err := src.ReadAll()
+	if err != nil {
+		return "", "", err
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(filepath.Join(cacheDir, "gozero"), 0700); err != nil {
+		return "", "", err
+	}
+	scriptDir, err := os.MkdirTemp(filepath.Join(cacheDir, "gozero"), "gozero-script-*")
+	if err != nil {
+		return "", "", err
+	}
+	script := filepath.Join(scriptDir, "script"+filepath.Ext(src.Filename))
+	if err := os.WriteFile(script, content, 0644); err != nil {
+		_ = os.RemoveAll(scriptDir)
+		return "", "", err
+	}
+	return scriptDir, script, nil
+}
+
+// systemdConfiguration returns the systemd configuration of the profile
+func (p *Profile) systemdConfiguration(src, input *gozero.Source) (*sandbox.Configuration, error) {
+	config := &sandbox.Configuration{Environment: p.environment(src, input)}
+	if s := p.Sandbox.Systemd; s != nil {
+		for _, property := range s.Properties {
+			rule := sandbox.Rule{Filter: sandbox.Filter(property.Name), Arg: sandbox.Arg{Type: sandbox.Value, Params: property.Value}}
+			if len(property.Values) > 0 {
+				rule.Arg = sandbox.Arg{Type: sandbox.Folders, Params: property.Values}
+			}
+			config.Rules = append(config.Rules, rule)
+		}
+	}
+	if p.Limits.Network == "none" {
+		config.Rules = append(config.Rules, sandbox.Rule{Filter: sandbox.PrivateNetwork, Arg: sandbox.Arg{Type: sandbox.Bool, Params: "yes"}})
+	}
+	if p.Limits.Memory != "" {
+		config.Rules = append(config.Rules, sandbox.Rule{Filter: sandbox.MemoryMax, Arg: sandbox.Arg{Type: sandbox.Value, Params: strings.ToUpper(p.Limits.Memory)}})
+	}
+	if p.Limits.CPUs != "" {
+		cpus, err := strconv.ParseFloat(p.Limits.CPUs, 64)
+		if err != nil || cpus <= 0 {
+			return nil, fmt.Errorf("invalid cpu limit %q", p.Limits.CPUs)
+		}
+		quota := strconv.FormatFloat(cpus*100, 'f', -1, 64) + "%"
+		config.Rules = append(config.Rules, sandbox.Rule{Filter: sandbox.CPUQuota, Arg: sandbox.Arg{Type: sandbox.Value, Params: quota}})
+	}
+	return config, nil
+}
@@ -0,0 +1,51 @@
+//go:build linux
+
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectdiscovery/gozero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemdExecutorEngine(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not installed")
+	}
+	// the first installed engine is used, like profiles without sandbox
+	profile := &Profile{Engines: []string{"missing-engine", "sh"}, Sandbox: Sandbox{Backend: BackendSystemd}}
+	executor, err := profile.Executor()
+	require.NoError(t, err)
+	require.Equal(t, sh, executor.(*systemdExecutor).engine)
+
+	profile.Engines = []string{"missing-engine"}
+	_, err = profile.Executor()
+	require.ErrorIs(t, err, gozero.ErrNoValidEngine)
+}
+
+func TestStageSystemdScript(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	src, err := gozero.NewSourceWithString("echo staged", "*.sh", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = src.Cleanup()
+	}()
+
+	// sources are usually in /tmp, hidden by units with PrivateTmp
+	scriptDir, script, err := stageSystemdScript(src)
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(scriptDir)
+	}()
+	require.Equal(t, filepath.Join(cacheDir, "gozero"), filepath.Dir(scriptDir))
+	require.Equal(t, ".sh", filepath.Ext(script))
+	data, err := os.ReadFile(script)
+	require.NoError(t, err)
+	require.Equal(t, "echo staged", string(data))
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package config
+
+import (
+	"errors"
+
+	"github.com/projectdiscovery/gozero/types"
+)
+
+func newBubblewrapExecutor(*Profile) (Executor, error) {
+	return nil, types.BackendUnavailableError(errors.New("bubblewrap is only available on linux"))
+}
+
+func newSystemdExecutor(*Profile) (Executor, error) {
+	return nil, types.BackendUnavailableError(errors.New("systemd sandbox is only available on linux"))
+}
@@ -0,0 +1,390 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/projectdiscovery/gozero/internal/runner"
+	"gopkg.in/yaml.v3"
+)
+
+// Error is a configuration error at a precise location of the file
+type Error struct {
+	File   string
+	Line   int
+	Column int
+	// Path of the invalid value (e.g. profiles.python.sandbox.backend)
+	Path    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	var sb strings.Builder
+	if e.File != "" {
+		sb.WriteString(e.File)
+		sb.WriteString(":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&sb, "%d:%d:", e.Line, e.Column)
+	}
+	if sb.Len() > 0 {
+		sb.WriteString(" ")
+	}
+	if e.Path != "" {
+		sb.WriteString(e.Path)
+		sb.WriteString(": ")
+	}
+	sb.WriteString(e.Message)
+	return sb.String()
+}
+
+// Errors are all the errors found in a configuration
+type Errors []*Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors for errors.Is and errors.As
+func (e Errors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+var (
+	configType   = reflect.TypeOf(Config{})
+	durationType = reflect.TypeOf(Duration(0))
+	envNameRe    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	yamlLineRe   = regexp.MustCompile(`line (\d+)`)
+)
+
+// validator collects errors with the location of the nodes they refer to
+type validator struct {
+	*loader
+	nodes map[string]*yaml.Node
+	errs  Errors
+}
+
+func (v *validator) errorf(node *yaml.Node, path, format string, args ...any) {
+	err := &Error{File: v.filename, Path: path, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		err.Line, err.Column = node.Line, node.Column
+	}
+	v.errs = append(v.errs, err)
+}
+
+// pathErrorf reports an error at the node of path or of its closest parent
+func (v *validator) pathErrorf(path, format string, args ...any) {
+	for p := path; ; {
+		if node, ok := v.nodes[p]; ok {
+			v.errorf(node, path, format, args...)
+			return
+		}
+		i := strings.LastIndexAny(p, ".[")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	v.errorf(nil, path, format, args...)
+}
+
+// syntaxError converts a yaml parse or decode error
+func (l *loader) syntaxError(err error) error {
+	line := 0
+	if match := yamlLineRe.FindStringSubmatch(err.Error()); match != nil {
+		line, _ = strconv.Atoi(match[1])
+	}
+	msg := strings.TrimPrefix(err.Error(), "yaml: ")
+	return Errors{{File: l.filename, Line: line, Message: msg}}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// interpolate expands environment variable references in scalar values
+func (v *validator) interpolate(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.interpolate(node.Content[i+1], joinPath(path, node.Content[i].Value))
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			v.interpolate(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case yaml.ScalarNode:
+		if strings.Contains(node.Value, "$") {
+			node.Value = v.expand(node, path)
+		}
+	}
+}
+
+// expand replaces ${NAME} and ${NAME:-default} in the node value, $$ is a literal $
+func (v *validator) expand(node *yaml.Node, path string) string {
+	s := node.Value
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] != '$':
+			sb.WriteByte(s[i])
+			i++
+		case strings.HasPrefix(s[i:], "$$"):
+			sb.WriteByte('$')
+			i += 2
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				v.errorf(node, path, "unterminated variable reference in %q", s)
+				return s
+			}
+			name, def, hasDef := strings.Cut(s[i+2:i+2+end], ":-")
+			if !envNameRe.MatchString(name) {
+				v.errorf(node, path, "invalid variable name %q", name)
+				return s
+			}
+			value, ok := v.lookupEnv(name)
+			if !ok || (hasDef && value == "") {
+				if !hasDef {
+					v.errorf(node, path, "environment variable %s is not set", name)
+					return s
+				}
+				value = def
+			}
+			sb.WriteString(value)
+			i += end + 3
+		default:
+			sb.WriteByte('$')
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// schema checks that the node matches the shape of typ and
+// records the node of every path for later errors
+func (v *validator) schema(node *yaml.Node, path string, typ reflect.Type) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	v.nodes[path] = node
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == durationType {
+		if v.expectKind(node, path, yaml.ScalarNode, "a duration (e.g. 30s)") {
+			var d Duration
+			if err := d.UnmarshalYAML(node); err != nil {
+				v.errorf(node, path, "%v", err)
+			}
+		}
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		if !v.expectKind(node, path, yaml.MappingNode, "a mapping") {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fieldByTag(typ, key.Value)
+			if !ok {
+				v.errorf(key, joinPath(path, key.Value), "unknown field %q (expected one of %s)", key.Value, strings.Join(fieldNames(typ), ", "))
+				continue
+			}
+			v.schema(value, joinPath(path, key.Value), field.Type)
+		}
+	case reflect.Map:
+		if !v.expectKind(node, path, yaml.MappingNode, "a mapping") {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.schema(node.Content[i+1], joinPath(path, node.Content[i].Value), typ.Elem())
+		}
+	case reflect.Slice:
+		if !v.expectKind(node, path, yaml.SequenceNode, "a list") {
+			return
+		}
+		for i, item := range node.Content {
+			v.schema(item, fmt.Sprintf("%s[%d]", path, i), typ.Elem())
+		}
+	case reflect.String:
+		v.expectKind(node, path, yaml.ScalarNode, "a string")
+	case reflect.Bool:
+		if v.expectKind(node, path, yaml.ScalarNode, "a boolean") && node.Tag != "!!bool" {
+			v.errorf(node, path, "expected a boolean, got %q", node.Value)
+		}
+	case reflect.Int:
+		if v.expectKind(node, path, yaml.ScalarNode, "an integer") && node.Tag != "!!int" {
+			v.errorf(node, path, "expected an integer, got %q", node.Value)
+		}
+	}
+}
+
+func (v *validator) expectKind(node *yaml.Node, path string, kind yaml.Kind, want string) bool {
+	if node.Kind == kind {
+		return true
+	}
+	v.errorf(node, path, "expected %s", want)
+	return false
+}
+
+func fieldByTag(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if tag, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); tag == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func fieldNames(typ reflect.Type) []string {
+	names := make([]string, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		if tag, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ","); tag != "" {
+			names = append(names, tag)
+		}
+	}
+	return names
+}
+
+// semantics validates the decoded configuration
+func (v *validator) semantics(config *Config) {
+	if len(config.Profiles) == 0 {
+		v.pathErrorf("profiles", "no profiles defined")
+		return
+	}
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := joinPath("profiles", name)
+		profile := config.Profiles[name]
+		if profile == nil {
+			v.pathErrorf(path, "empty profile")
+			continue
+		}
+		v.profile(path, profile)
+	}
+}
+
+func (v *validator) profile(path string, p *Profile) {
+	switch {
+	case len(p.Engines) == 0 && p.Language == "":
+		v.pathErrorf(path, "one of engines or language is required")
+	case len(p.Engines) > 0 && p.Language != "":
+		v.pathErrorf(joinPath(path, "language"), "engines and language are mutually exclusive")
+	case p.Language != "":
+		if _, ok := runner.Languages[p.Language]; !ok {
+			v.pathErrorf(joinPath(path, "language"), "unknown language %q (supported: %s)", p.Language, strings.Join(runner.LanguageNames(), ", "))
+		}
+	}
+	for i, engine := range p.Engines {
+		if engine == "" {
+			v.pathErrorf(fmt.Sprintf("%s.engines[%d]", path, i), "empty engine")
+		}
+	}
+	for i, name := range p.Env.Allow {
+		if !envNameRe.MatchString(name) {
+			v.pathErrorf(fmt.Sprintf("%s.env.allow[%d]", path, i), "invalid variable name %q", name)
+		}
+	}
+	for name := range p.Env.Set {
+		if !envNameRe.MatchString(name) {
+			v.pathErrorf(joinPath(path, "env.set."+name), "invalid variable name %q", name)
+		}
+	}
+
+	sandbox := joinPath(path, "sandbox")
+	backend := p.Sandbox.backend()
+	settings := map[string]bool{
+		BackendDocker:     p.Sandbox.Docker != nil,
+		BackendBubblewrap: p.Sandbox.Bubblewrap != nil,
+		BackendSystemd:    p.Sandbox.Systemd != nil,
+	}
+	for _, name := range []string{BackendDocker, BackendBubblewrap, BackendSystemd} {
+		if settings[name] && name != backend {
+			v.pathErrorf(joinPath(sandbox, name), "%s settings require backend %s", name, name)
+		}
+	}
+	if p.Env.Inherit != nil && *p.Env.Inherit && backend != BackendNone {
+		v.pathErrorf(joinPath(path, "env.inherit"), "inheriting the environment is not supported by the %s backend", backend)
+	}
+
+	limits := joinPath(path, "limits")
+	switch backend {
+	case BackendNone:
+		for _, limit := range []struct{ name, value string }{{"memory", p.Limits.Memory}, {"cpus", p.Limits.CPUs}, {"network", p.Limits.Network}} {
+			if limit.value != "" {
+				v.pathErrorf(joinPath(limits, limit.name), "%s limit requires a sandbox backend", limit.name)
+			}
+		}
+	case BackendDocker:
+		if p.Language == "" && (p.Sandbox.Docker == nil || p.Sandbox.Docker.Image == "") {
+			v.pathErrorf(sandbox, "docker.image is required when language is not set")
+		}
+	case BackendBubblewrap:
+		for _, limit := range []struct{ name, value string }{{"memory", p.Limits.Memory}, {"cpus", p.Limits.CPUs}} {
+			if limit.value != "" {
+				v.pathErrorf(joinPath(limits, limit.name), "%s limit is not supported by the bwrap backend", limit.name)
+			}
+		}
+		if p.Limits.Network != "" && p.Limits.Network != "none" {
+			v.pathErrorf(joinPath(limits, "network"), "the bwrap backend has no network access (use none)")
+		}
+		if s := p.Sandbox.Bubblewrap; s != nil {
+			for i, bind := range s.ReadOnlyBinds {
+				if bind.Host == "" || bind.Sandbox == "" {
+					v.pathErrorf(fmt.Sprintf("%s.bwrap.read_only_binds[%d]", sandbox, i), "host and sandbox paths are required")
+				}
+			}
+		}
+	case BackendSystemd:
+		if p.Limits.Network != "" && p.Limits.Network != "none" {
+			v.pathErrorf(joinPath(limits, "network"), "the systemd backend only supports network none")
+		}
+		if s := p.Sandbox.Systemd; s != nil {
+			for i, property := range s.Properties {
+				propertyPath := fmt.Sprintf("%s.systemd.properties[%d]", sandbox, i)
+				if property.Name == "" {
+					v.pathErrorf(propertyPath, "property name is required")
+				}
+				if (property.Value == "") == (len(property.Values) == 0) {
+					v.pathErrorf(propertyPath, "exactly one of value or values is required")
+				}
+			}
+		}
+	default:
+		v.pathErrorf(joinPath(sandbox, "backend"), "unknown backend %q (supported: %s)", p.Sandbox.Backend, strings.Join(runner.Backends, ", "))
+	}
+}
+
+// backend returns the backend, defaulting to none
+func (s *Sandbox) backend() string {
+	if s.Backend == "" {
+		return BackendNone
+	}
+	return s.Backend
+}
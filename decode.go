@@ -0,0 +1,19 @@
+package gozero
+
+import (
+	"context"
+
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// EvalJSON evaluates the source code and decodes its stdout as JSON into a T.
+// The result is returned alongside so that stderr and exit code remain available
+func EvalJSON[T any](ctx context.Context, g *Gozero, src, input *Source, args ...string) (T, *types.Result, error) {
+	var v T
+	res, err := g.Eval(ctx, src, input, args...)
+	if err != nil {
+		return v, res, err
+	}
+	v, err = types.JSON[T](res)
+	return v, res, err
+}
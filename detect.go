@@ -0,0 +1,251 @@
+package gozero
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Language describes the engines and file extensions of a language
+type Language struct {
+	Engines    []string
+	Extensions []string
+}
+
+// Languages are the languages detected by MultiEngine
+var Languages = map[string]Language{
+	"python":     {Engines: []string{"python3", "python"}, Extensions: []string{".py", ".pyw"}},
+	"javascript": {Engines: []string{"node", "nodejs"}, Extensions: []string{".js", ".mjs", ".cjs"}},
+	"bash":       {Engines: []string{"bash"}, Extensions: []string{".bash"}},
+	"sh":         {Engines: []string{"sh", "bash"}, Extensions: []string{".sh"}},
+	"ruby":       {Engines: []string{"ruby"}, Extensions: []string{".rb"}},
+	"php":        {Engines: []string{"php"}, Extensions: []string{".php"}},
+	"perl":       {Engines: []string{"perl"}, Extensions: []string{".pl", ".pm"}},
+	"powershell": {Engines: []string{"pwsh", "powershell"}, Extensions: []string{".ps1"}},
+}
+
+// languageAliases are alternative names of languages
+var languageAliases = map[string]string{
+	"py":    "python",
+	"node":  "javascript",
+	"js":    "javascript",
+	"shell": "sh",
+	"rb":    "ruby",
+	"pwsh":  "powershell",
+}
+
+// Detection methods of a source language
+const (
+	DetectedByHint      = "hint"
+	DetectedByShebang   = "shebang"
+	DetectedByExtension = "extension"
+	DetectedByContent   = "content"
+)
+
+// Detection is the language and engines detected for a source
+type Detection struct {
+	// Language of the source (empty when a shebang interpreter is not a known language)
+	Language string
+	// Engines to try in order
+	Engines []string
+	// Args of the shebang line, passed to the engine before Options.Args
+	Args []string
+	// Method is how the language was detected (e.g. shebang)
+	Method string
+}
+
+// maxDetectBytes is the max size of a source read for content heuristics
+const maxDetectBytes = 64 * 1024
+
+// DetectLanguage detects the language and engines of src from, in order, its
+// language hint, shebang line, file extension and content. It returns
+// ErrUnknownLanguage when none of them identifies a language
+func DetectLanguage(src *Source) (*Detection, error) {
+	if src.Language != "" {
+		name, ok := lookupLanguage(src.Language)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownLanguage, src.Language)
+		}
+		return &Detection{Language: name, Engines: Languages[name].Engines, Method: DetectedByHint}, nil
+	}
+
+	head, err := readHead(src.Filename)
+	if err != nil {
+		return nil, err
+	}
+	if detection, ok := detectShebang(head); ok {
+		return detection, nil
+	}
+	ext := strings.ToLower(filepath.Ext(src.Filename))
+	for name, language := range Languages {
+		for _, extension := range language.Extensions {
+			if ext == extension {
+				return &Detection{Language: name, Engines: language.Engines, Method: DetectedByExtension}, nil
+			}
+		}
+	}
+	if name, ok := detectContent(head); ok {
+		return &Detection{Language: name, Engines: Languages[name].Engines, Method: DetectedByContent}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownLanguage, src.Filename)
+}
+
+// lookupLanguage returns the name of a language or of one of its aliases
+func lookupLanguage(name string) (string, bool) {
+	name = strings.ToLower(name)
+	if alias, ok := languageAliases[name]; ok {
+		name = alias
+	}
+	_, ok := Languages[name]
+	return name, ok
+}
+
+// languageOfEngine returns the language run by engine (e.g. /usr/bin/python3.11)
+func languageOfEngine(engine string) string {
+	base := strings.TrimSuffix(strings.ToLower(filepath.Base(engine)), ".exe")
+	if name, ok := lookupLanguage(base); ok {
+		return name
+	}
+	for name, language := range Languages {
+		for _, candidate := range language.Engines {
+			if base == candidate {
+				return name
+			}
+		}
+	}
+	// versioned interpreters (e.g. python3.11)
+	if strings.HasPrefix(base, "python") {
+		return "python"
+	}
+	return ""
+}
+
+// readHead reads the beginning of the file used for detection
+func readHead(filename string) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return io.ReadAll(io.LimitReader(file, maxDetectBytes))
+}
+
+// detectShebang parses the #! line of content. The interpreter is tried first,
+// followed by its base name and the engines of its language. Interpreters of
+// unknown languages are ignored since the shebang could name any host binary
+func detectShebang(content []byte) (*Detection, bool) {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return nil, false
+	}
+	line, _, _ := bytes.Cut(content[2:], []byte("\n"))
+	fields := strings.Fields(strings.TrimSuffix(string(line), "\r"))
+	if len(fields) > 0 && filepath.Base(fields[0]) == "env" {
+		// #!/usr/bin/env [-S] [NAME=value] interpreter args
+		fields = fields[1:]
+		for len(fields) > 0 && (strings.HasPrefix(fields[0], "-") || strings.Contains(fields[0], "=")) {
+			fields = fields[1:]
+		}
+	}
+	if len(fields) == 0 {
+		return nil, false
+	}
+	interpreter := fields[0]
+	language := languageOfEngine(interpreter)
+	if language == "" {
+		return nil, false
+	}
+	detection := &Detection{
+		Language: language,
+		Engines:  []string{interpreter},
+		Args:     fields[1:],
+		Method:   DetectedByShebang,
+	}
+	if base := filepath.Base(interpreter); base != interpreter {
+		// the interpreter may be installed elsewhere on this host
+		detection.Engines = append(detection.Engines, base)
+	}
+	for _, engine := range Languages[language].Engines {
+		if !slices.Contains(detection.Engines, engine) {
+			detection.Engines = append(detection.Engines, engine)
+		}
+	}
+	return detection, true
+}
+
+// contentHints score the languages of a source, one point per matching line
+var contentHints = map[string][]*regexp.Regexp{
+	"python": {
+		regexp.MustCompile(`^(?:from\s+[\w.]+\s+)?import\s+[\w.]+(?:\s+as\s+\w+)?\s*$`),
+		regexp.MustCompile(`^\s*(?:def|class)\s+\w+.*:\s*$`),
+		regexp.MustCompile(`^\s*(?:if|elif|for|while|with|try|except)\b.*:\s*$`),
+		regexp.MustCompile(`^\s*print\(`),
+		regexp.MustCompile(`__name__\s*==`),
+	},
+	"javascript": {
+		regexp.MustCompile(`\bconsole\.\w+\(`),
+		regexp.MustCompile(`\brequire\(\s*['"]`),
+		regexp.MustCompile(`^\s*(?:const|let|var)\s+[\w{}\[\], ]+\s*=`),
+		regexp.MustCompile(`^\s*(?:async\s+)?function\s*\w*\s*\(`),
+		regexp.MustCompile(`=>\s*[{(]?`),
+		regexp.MustCompile(`^\s*(?:import|export)\s.*\bfrom\s+['"]`),
+	},
+	"sh": {
+		regexp.MustCompile(`^\s*(?:echo|printf|export|exit|cd|set|read)\b`),
+		regexp.MustCompile(`^\s*(?:if|while|for)\s.*;\s*(?:then|do)\s*$`),
+		regexp.MustCompile(`^\s*(?:fi|done|esac|then|do)\s*$`),
+		regexp.MustCompile(`\$\(|\$\{\w+`),
+		regexp.MustCompile(`^\s*\w+=\S*\s*$`),
+	},
+	"ruby": {
+		regexp.MustCompile(`^\s*(?:puts|require|require_relative)\s+['"]?`),
+		regexp.MustCompile(`^\s*def\s+\w+[^:]*$`),
+		regexp.MustCompile(`^\s*end\s*$`),
+		regexp.MustCompile(`\.each\s+do\b|\bdo\s*\|\w+\|`),
+	},
+	"perl": {
+		regexp.MustCompile(`^\s*use\s+(?:strict|warnings)\s*;`),
+		regexp.MustCompile(`^\s*my\s+[$@%]\w+`),
+	},
+}
+
+// detectContent returns the language scoring the most content hints.
+// Ties are not resolved
+func detectContent(content []byte) (string, bool) {
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("<?php")) {
+		return "php", true
+	}
+	scores := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxDetectBytes)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for name, hints := range contentHints {
+			for _, hint := range hints {
+				if hint.MatchString(line) {
+					scores[name]++
+				}
+			}
+		}
+	}
+	best, bestScore, tie := "", 0, false
+	for name, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = name, score, false
+		case score == bestScore:
+			tie = true
+		}
+	}
+	if bestScore == 0 || tie {
+		return "", false
+	}
+	return best, true
+}
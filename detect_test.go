@@ -0,0 +1,152 @@
+package gozero
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	osutils "github.com/projectdiscovery/utils/os"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		hint     string
+		want     Detection
+	}{
+		{
+			name: "hint", filename: "script.sh", content: "print(1)\n", hint: "py",
+			want: Detection{Language: "python", Engines: []string{"python3", "python"}, Method: DetectedByHint},
+		},
+		{
+			name: "env shebang", filename: "script", content: "#!/usr/bin/env -S python3 -u\nprint(1)\n",
+			want: Detection{Language: "python", Engines: []string{"python3", "python"}, Args: []string{"-u"}, Method: DetectedByShebang},
+		},
+		{
+			name: "shebang", filename: "script.py", content: "#!/bin/bash -e\necho 1\n",
+			want: Detection{Language: "bash", Engines: []string{"/bin/bash", "bash"}, Args: []string{"-e"}, Method: DetectedByShebang},
+		},
+		{
+			// unknown interpreters could be any host binary
+			name: "unknown shebang", filename: "script.sh", content: "#!/bin/rm -rf /x\necho 1\n",
+			want: Detection{Language: "sh", Engines: []string{"sh", "bash"}, Method: DetectedByExtension},
+		},
+		{
+			name: "extension", filename: "script.rb", content: "puts 1\n",
+			want: Detection{Language: "ruby", Engines: []string{"ruby"}, Method: DetectedByExtension},
+		},
+		{
+			name: "python content", filename: "python-content", content: "import sys\n\ndef main():\n    print(sys.argv)\n",
+			want: Detection{Language: "python", Engines: []string{"python3", "python"}, Method: DetectedByContent},
+		},
+		{
+			name: "javascript content", filename: "javascript-content", content: "const fs = require('fs')\nconsole.log(fs)\n",
+			want: Detection{Language: "javascript", Engines: []string{"node", "nodejs"}, Method: DetectedByContent},
+		},
+		{
+			name: "shell content", filename: "shell-content", content: "NAME=world\nif [ -n \"$NAME\" ]; then\n  echo \"hello ${NAME}\"\nfi\n",
+			want: Detection{Language: "sh", Engines: []string{"sh", "bash"}, Method: DetectedByContent},
+		},
+		{
+			name: "php content", filename: "php-content", content: "<?php\necho 1;\n",
+			want: Detection{Language: "php", Engines: []string{"php"}, Method: DetectedByContent},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, tt.filename)
+			require.NoError(t, os.WriteFile(filename, []byte(tt.content), 0600))
+			detection, err := DetectLanguage(&Source{Filename: filename, Language: tt.hint})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, *detection)
+		})
+	}
+
+	filename := filepath.Join(dir, "unknown")
+	require.NoError(t, os.WriteFile(filename, []byte("???\n"), 0600))
+	_, err := DetectLanguage(&Source{Filename: filename})
+	require.ErrorIs(t, err, ErrUnknownLanguage)
+	_, err = DetectLanguage(&Source{Filename: filename, Language: "cobol"})
+	require.ErrorIs(t, err, ErrUnknownLanguage)
+	require.NoError(t, os.WriteFile(filename, []byte("#!/bin/rm -rf /x\n"), 0600))
+	_, err = DetectLanguage(&Source{Filename: filename})
+	require.ErrorIs(t, err, ErrUnknownLanguage)
+}
+
+func TestMultiEngine(t *testing.T) {
+	if osutils.IsWindows() {
+		t.Skip("requires sh")
+	}
+	multi := NewMultiEngine(&Options{Engines: []string{"sh"}})
+	eval := func(code, language string) (string, error) {
+		src, err := NewSourceWithString(code, "", "")
+		require.Nil(t, err)
+		defer func() {
+			_ = src.Cleanup()
+		}()
+		src.Language = language
+		input, err := NewSource()
+		require.Nil(t, err)
+		defer func() {
+			_ = input.Cleanup()
+		}()
+		res, err := multi.Eval(context.Background(), src, input)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(res.Stdout.String()), nil
+	}
+
+	out, err := eval("#!/usr/bin/env python3\nimport sys\nprint(sys.version_info[0])\n", "")
+	require.NoError(t, err)
+	require.Equal(t, "3", out)
+
+	out, err = eval("import sys\n\nif True:\n    print('content')\n", "")
+	require.NoError(t, err)
+	require.Equal(t, "content", out)
+
+	// unknown languages fall back to the engines of the options
+	out, err = eval("echo fallback", "")
+	require.NoError(t, err)
+	require.Equal(t, "fallback", out)
+
+	// engines are cached per detection
+	require.Len(t, multi.engines, 2)
+	_, err = eval("print(1)", "python")
+	require.NoError(t, err)
+	require.Len(t, multi.engines, 2)
+
+	_, err = eval("print(1)", "powershell")
+	if err != nil {
+		require.ErrorIs(t, err, ErrNoValidEngine)
+		require.Contains(t, err.Error(), "for powershell source (tried pwsh, powershell)")
+	}
+}
+
+func TestMultiEngineSharedOptions(t *testing.T) {
+	if osutils.IsWindows() {
+		t.Skip("requires sh")
+	}
+	// options already passed to New must not leak their engine
+	opts := &Options{Engines: []string{"sh"}}
+	_, err := New(opts)
+	require.NoError(t, err)
+	src, err := NewSourceWithString("print(1)", "", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = src.Cleanup()
+	}()
+	src.Language = "powershell"
+	g, _, err := NewMultiEngine(opts).Engine(src)
+	if err == nil {
+		require.NotEqual(t, opts.engine, g.Options.engine)
+		t.Skip("powershell installed")
+	}
+	require.ErrorIs(t, err, ErrNoValidEngine)
+}
@@ -8,4 +8,10 @@ var (
 
 	// ErrNoEngines is returned when no engines are provided
 	ErrNoEngines = errors.New("no engines provided")
+
+	// ErrSandboxUnsupported is returned when a feature cannot be used with Options.Sandbox
+	ErrSandboxUnsupported = errors.New("not supported with Options.Sandbox")
+
+	// ErrUnknownLanguage is returned when the language of a source cannot be detected
+	ErrUnknownLanguage = errors.New("unknown source language")
 )
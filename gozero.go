@@ -3,11 +3,20 @@ package gozero
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"sync"
+	"time"
 
 	"github.com/projectdiscovery/gozero/cmdexec"
+	"github.com/projectdiscovery/gozero/internal/telemetry"
+	"github.com/projectdiscovery/gozero/metrics"
+	"github.com/projectdiscovery/gozero/policy"
 	"github.com/projectdiscovery/gozero/sandbox"
 	"github.com/projectdiscovery/gozero/types"
+	"github.com/projectdiscovery/utils/errkit"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // VirtualEnvType represents the type of virtual environment
@@ -20,9 +29,28 @@ const (
 	VirtualEnvDocker
 )
 
+// String returns the name of the virtual environment type
+func (v VirtualEnvType) String() string {
+	switch v {
+	case VirtualEnvLinux:
+		return "linux"
+	case VirtualEnvDarwin:
+		return "darwin"
+	case VirtualEnvWindows:
+		return "windows"
+	case VirtualEnvDocker:
+		return telemetry.BackendDocker
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(v))
+	}
+}
+
 // Gozero is executor for gozero
 type Gozero struct {
 	Options *Options
+
+	versionOnce sync.Once
+	version     string
 }
 
 // New creates a new gozero executor
@@ -30,6 +58,8 @@ func New(options *Options) (*Gozero, error) {
 	if len(options.Engines) == 0 {
 		return nil, ErrNoEngines
 	}
+	// options may have been used by a previous call
+	options.engine = ""
 	// attempt to locate the interpreter by executing it
 	for _, engine := range options.Engines {
 		// use lookpath to check if engine is available
@@ -50,7 +80,95 @@ func New(options *Options) (*Gozero, error) {
 
 // Eval evaluates the source code and returns the output
 // input = stdin , src = source code , args = arguments
-func (g *Gozero) Eval(ctx context.Context, src, input *Source, args ...string) (*types.Result, error) {
+// With Options.Sandbox the evaluation runs in the default sandbox of the
+// platform and fails when none is available
+func (g *Gozero) Eval(ctx context.Context, src, input *Source, args ...string) (res *types.Result, err error) {
+	backend, eval, sandboxErr := telemetry.BackendLocal, evalFunc(g.evalLocal), error(nil)
+	if g.Options.Sandbox {
+		backend, eval, sandboxErr = g.defaultSandbox()
+	}
+	ctx, span := telemetry.Tracer(g.Options.TracerProvider).Start(ctx, "gozero.Eval",
+		trace.WithAttributes(
+			telemetry.AttrEngine.String(g.Options.engine),
+			telemetry.AttrBackend.String(backend),
+		),
+	)
+	started := time.Now()
+	observe := metrics.Observe(ctx, g.Options.Metrics, g.Options.engine, backend)
+	defer func() {
+		if auditErr := g.auditEval(ctx, started, backend, g.Options.Args, src, input, args, res, err); auditErr != nil {
+			err = errkit.Append(err, errkit.WithMessage(auditErr, "failed to write audit record"))
+		}
+		observe(res, err)
+		telemetry.End(span, res, err)
+	}()
+
+	if sandboxErr != nil {
+		return nil, sandboxErr
+	}
+	var sandboxPolicy *policy.Sandbox
+	if g.Options.Sandbox {
+		switch {
+		case g.Options.PTY != nil:
+			return nil, fmt.Errorf("%w: pty", ErrSandboxUnsupported)
+		case g.Options.Credential != nil:
+			return nil, fmt.Errorf("%w: credential", ErrSandboxUnsupported)
+		case g.Options.Transcript:
+			return nil, fmt.Errorf("%w: transcript", ErrSandboxUnsupported)
+		case g.Options.PreferStartProcess:
+			return nil, fmt.Errorf("%w: prefer start process", ErrSandboxUnsupported)
+		case g.Options.WorkDir != "" && backend != telemetry.BackendSystemd:
+			return nil, fmt.Errorf("%w: work dir with %s", ErrSandboxUnsupported, backend)
+		}
+		sandboxPolicy = &policy.Sandbox{Backend: backend, NetworkDisabled: true}
+	}
+	if err := g.enforcePolicy(g.Options.engine, g.Options.Args, src, input, args, sandboxPolicy); err != nil {
+		return nil, err
+	}
+	if err := g.preflight(ctx, src); err != nil {
+		return nil, err
+	}
+	res, err = eval(ctx, src, input, args)
+	return res, g.normalizeOutput(res, err)
+}
+
+// evalLocal evaluates src with the engine on the host
+func (g *Gozero) evalLocal(ctx context.Context, src, input *Source, args []string) (*types.Result, error) {
+	gcmd, err := g.command(src, input, args)
+	if err != nil {
+		// returns error if binary(engine) does not exist
+		return nil, err
+	}
+	if g.Options.PTY != nil {
+		gcmd.SetPTY(g.Options.PTY)
+	}
+	gcmd.SetStdin(input.File) // stdin
+	return types.Retry(ctx, g.Options.Retry, func(ctx context.Context, attempt int) (*types.Result, error) {
+		if attempt > 1 && input.File != nil {
+			// replay stdin from the start
+			if _, err := input.File.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		return gcmd.Execute(ctx)
+	})
+}
+
+// normalizeOutput transcodes the result output to UTF-8 when enabled
+// and returns err combined with any transcoding error
+func (g *Gozero) normalizeOutput(res *types.Result, err error) error {
+	if !g.Options.NormalizeCharset || res == nil {
+		return err
+	}
+	if normErr := res.NormalizeCharset(); normErr != nil {
+		return errkit.Append(err, errkit.WithMessage(normErr, "failed to normalize output charset"))
+	}
+	return err
+}
+
+// command builds the engine command evaluating src with args and
+// the src and input (optional) variables as environment variables
+func (g *Gozero) command(src, input *Source, args []string) (*cmdexec.Command, error) {
 	if g.Options.EarlyCloseFileDescriptor {
 		_ = src.File.Close()
 	}
@@ -60,22 +178,56 @@ func (g *Gozero) Eval(ctx context.Context, src, input *Source, args ...string) (
 	allargs = append(allargs, args...)
 	gcmd, err := cmdexec.NewCommand(g.Options.engine, allargs...)
 	if err != nil {
-		// returns error if binary(engine) does not exist
 		return nil, err
 	}
 	if g.Options.DebugMode {
 		gcmd.EnableDebugMode()
 	}
-	gcmd.SetStdin(input.File) // stdin
+	gcmd.SetTracerProvider(g.Options.TracerProvider)
+	gcmd.SetTranscript(g.Options.Transcript)
+	gcmd.SetCleanEnv(g.Options.CleanEnv)
+	gcmd.SetPreferStartProcess(g.Options.PreferStartProcess)
+	gcmd.SetDir(g.Options.WorkDir)
+	if g.Options.Credential != nil {
+		// sources are created readable by the current user only
+		if err := os.Chown(src.Filename, int(g.Options.Credential.Uid), int(g.Options.Credential.Gid)); err != nil {
+			return nil, errkit.WithMessage(types.StartError(err), "failed to share source with credential")
+		}
+		gcmd.SetCredential(g.Options.Credential)
+	}
+	gcmd.SetSuccessCriteria(g.Options.SuccessCriteria)
 	// add both input and src variables if any
 	gcmd.AddVars(src.Variables...) // variables as environment variables
-	gcmd.AddVars(input.Variables...)
-	return gcmd.Execute(ctx)
+	if input != nil {
+		gcmd.AddVars(input.Variables...)
+	}
+	return gcmd, nil
 }
 
 // EvalWithVirtualEnv evaluates the source code in a virtual environment and returns the output
 // This function passes the source code into the virtual environment and external parameters as environment variables
-func (g *Gozero) EvalWithVirtualEnv(ctx context.Context, envType VirtualEnvType, src, input *Source, dockerConfig *sandbox.DockerConfiguration, args ...string) (*types.Result, error) {
+func (g *Gozero) EvalWithVirtualEnv(ctx context.Context, envType VirtualEnvType, src, input *Source, dockerConfig *sandbox.DockerConfiguration, args ...string) (res *types.Result, err error) {
+	ctx, span := telemetry.Tracer(g.Options.TracerProvider).Start(ctx, "gozero.EvalWithVirtualEnv",
+		trace.WithAttributes(
+			telemetry.AttrEngine.String(g.Options.engine),
+			telemetry.AttrBackend.String(envType.String()),
+		),
+	)
+	started := time.Now()
+	defer func() {
+		var config any
+		if dockerConfig != nil {
+			// variables are recorded (redacted) separately
+			redacted := *dockerConfig
+			redacted.Environment = nil
+			config = redacted
+		}
+		if auditErr := g.auditEval(ctx, started, envType.String(), config, src, input, args, res, err); auditErr != nil {
+			err = errkit.Append(err, errkit.WithMessage(auditErr, "failed to write audit record"))
+		}
+		telemetry.End(span, res, err)
+	}()
+
 	// Read source code content
 	srcContent, err := src.ReadAll()
 	if err != nil {
@@ -100,11 +252,18 @@ func (g *Gozero) EvalWithVirtualEnv(ctx context.Context, envType VirtualEnvType,
 	case VirtualEnvDocker:
 		// Update Docker configuration with environment variables
 		dockerConfig.Environment = envVars
-
-		// Create Docker sandbox with updated configuration
-		dockerSandbox, err := sandbox.NewDockerSandbox(ctx, dockerConfig)
-		if err != nil {
-			return nil, err
+		if dockerConfig.TracerProvider == nil {
+			dockerConfig.TracerProvider = g.Options.TracerProvider
+		}
+		// execution metrics are emitted by the sandbox itself
+		if dockerConfig.Metrics == nil {
+			dockerConfig.Metrics = g.Options.Metrics
+		}
+		if g.Options.Transcript {
+			dockerConfig.Transcript = true
+		}
+		if dockerConfig.SuccessCriteria == nil {
+			dockerConfig.SuccessCriteria = g.Options.SuccessCriteria
 		}
 
 		// Use the engine as the interpreter
@@ -118,13 +277,37 @@ func (g *Gozero) EvalWithVirtualEnv(ctx context.Context, envType VirtualEnvType,
 			}
 		}
 
-		// Execute the source code in the Docker container
-		result, err := dockerSandbox.RunSource(ctx, string(srcContent), interpreter)
+		if err := g.enforcePolicy(interpreter, nil, src, input, args, &policy.Sandbox{
+			Backend:         telemetry.BackendDocker,
+			NetworkDisabled: dockerConfig.NetworkDisabled || dockerConfig.NetworkMode == "none",
+			Config:          dockerConfig,
+		}); err != nil {
+			return nil, err
+		}
+		// syntax errors are reported before spinning up the sandbox
+		if err := g.preflight(ctx, src); err != nil {
+			return nil, err
+		}
+
+		// runs are retried by the sandbox, only its creation is retried
+		// here (e.g. docker daemon busy) so attempts do not multiply
+		if dockerConfig.Retry == nil {
+			dockerConfig.Retry = g.Options.Retry
+		}
+		var dockerSandbox *sandbox.SandboxDocker
+		_, err = types.Retry(ctx, g.Options.Retry, func(ctx context.Context, _ int) (*types.Result, error) {
+			// Create Docker sandbox with updated configuration
+			var err error
+			dockerSandbox, err = sandbox.NewDockerSandbox(ctx, dockerConfig)
+			return nil, err
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		return result, nil
+		// Execute the source code in the Docker container
+		res, err = dockerSandbox.RunSource(ctx, string(srcContent), interpreter)
+		return res, g.normalizeOutput(res, err)
 
 	case VirtualEnvLinux, VirtualEnvDarwin, VirtualEnvWindows:
 		// For now, these are not implemented - they would use the regular Eval method
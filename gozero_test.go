@@ -1,12 +1,30 @@
 package gozero
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/projectdiscovery/gozero/audit"
+	"github.com/projectdiscovery/gozero/cmdexec"
+	"github.com/projectdiscovery/gozero/internal/telemetry"
+	"github.com/projectdiscovery/gozero/policy"
+	"github.com/projectdiscovery/gozero/preflight"
+	"github.com/projectdiscovery/gozero/sandbox"
+	"github.com/projectdiscovery/gozero/types"
 	osutils "github.com/projectdiscovery/utils/os"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestEval(t *testing.T) {
@@ -56,3 +74,514 @@ func TestErr(t *testing.T) {
 	require.Nil(t, err)
 	require.NotNil(t, gozero)
 }
+
+func TestEvalTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() {
+		_ = tp.Shutdown(context.Background())
+	}()
+
+	opts := &Options{TracerProvider: tp}
+	if osutils.IsWindows() {
+		opts.Engines = []string{"python3.exe"}
+	} else {
+		opts.Engines = []string{"python3"}
+	}
+	pyzero, err := New(opts)
+	require.Nil(t, err)
+	src, err := NewSourceWithString(`print("hello")`, "", "")
+	require.Nil(t, err)
+	defer func() {
+		_ = src.Cleanup()
+	}()
+	input, err := NewSource()
+	require.Nil(t, err)
+	defer func() {
+		_ = input.Cleanup()
+	}()
+
+	_, err = pyzero.Eval(context.Background(), src, input)
+	require.Nil(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	byName := map[string]tracetest.SpanStub{}
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+	evalSpan, ok := byName["gozero.Eval"]
+	require.True(t, ok)
+	execSpan, ok := byName["cmdexec.Command.Execute"]
+	require.True(t, ok)
+	require.Equal(t, evalSpan.SpanContext.SpanID(), execSpan.Parent.SpanID())
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range evalSpan.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	require.Equal(t, "local", attrs[telemetry.AttrBackend].AsString())
+	require.Equal(t, opts.engine, attrs[telemetry.AttrEngine].AsString())
+	require.Equal(t, int64(0), attrs[telemetry.AttrExitCode].AsInt64())
+	require.Equal(t, int64(len("hello\n")), attrs[telemetry.AttrStdoutBytes].AsInt64())
+}
+
+func TestEvalAudit(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err := audit.NewLogger(logPath)
+	require.Nil(t, err)
+
+	opts := &Options{Engines: []string{"python3"}, Audit: logger}
+	pyzero, err := New(opts)
+	require.Nil(t, err)
+	src, err := NewSourceWithString(`import os; print(os.environ["SECRET"])`, "", "")
+	require.Nil(t, err)
+	defer func() {
+		_ = src.Cleanup()
+	}()
+	src.AddVariable(types.Variable{Name: "SECRET", Value: "hunter2"})
+	input, err := NewSource()
+	require.Nil(t, err)
+	defer func() {
+		_ = input.Cleanup()
+	}()
+
+	_, err = pyzero.Eval(context.Background(), src, input)
+	require.Nil(t, err)
+	require.Nil(t, logger.Close())
+
+	count, err := audit.Verify(logPath)
+	require.Nil(t, err)
+	require.Equal(t, 1, count)
+	data, err := os.ReadFile(logPath)
+	require.Nil(t, err)
+	require.NotContains(t, string(data), "hunter2")
+	require.Contains(t, string(data), audit.SHA256([]byte("hunter2\n")))
+}
+
+func TestAuditUnreadableSource(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err := audit.NewLogger(logPath)
+	require.Nil(t, err)
+	pyzero, err := New(&Options{Engines: []string{"python3"}, Audit: logger})
+	require.Nil(t, err)
+	src, err := NewSourceWithString("print(1)", "", "")
+	require.Nil(t, err)
+	require.Nil(t, src.Cleanup())
+
+	// the record is still written, without the source digest
+	require.Nil(t, pyzero.auditEval(context.Background(), time.Now(), "docker", nil, src, nil, nil, nil, nil))
+	require.Nil(t, logger.Close())
+	count, err := audit.Verify(logPath)
+	require.Nil(t, err)
+	require.Equal(t, 1, count)
+
+	data, err := os.ReadFile(logPath)
+	require.Nil(t, err)
+	var record audit.Record
+	require.Nil(t, json.Unmarshal(data, &record))
+	require.Empty(t, record.SourceSHA256)
+	require.Contains(t, record.Error, "failed to read source")
+	// the version of the host engine is not the one of the container
+	require.Empty(t, record.EngineVersion)
+}
+
+func TestAuditDockerConfig(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err := audit.NewLogger(logPath)
+	require.Nil(t, err)
+	pyzero, err := New(&Options{Engines: []string{"python3"}, Audit: logger})
+	require.Nil(t, err)
+	src, err := NewSourceWithString("print(1)", "", "")
+	require.Nil(t, err)
+	defer func() {
+		_ = src.Cleanup()
+	}()
+
+	// retry classifiers are not part of the config digest
+	config := sandbox.DockerConfiguration{
+		Image: "python:3-slim",
+		Retry: &types.RetryPolicy{MaxAttempts: 2, Retryable: func(error) bool { return true }},
+	}
+	require.Nil(t, pyzero.auditEval(context.Background(), time.Now(), "docker", config, src, nil, nil, nil, nil))
+	require.Nil(t, logger.Close())
+	count, err := audit.Verify(logPath)
+	require.Nil(t, err)
+	require.Equal(t, 1, count)
+}
+
+func TestStart(t *testing.T) {
+	pyzero, err := New(&Options{Engines: []string{"python3"}})
+	require.Nil(t, err)
+	src, err := NewSourceWithString(`
+import sys
+for line in sys.stdin:
+    print(line.strip().upper(), flush=True)
+`, "", "")
+	require.Nil(t, err)
+
+	proc, err := pyzero.Start(context.Background(), src, nil)
+	require.Nil(t, err)
+	require.NotZero(t, proc.Pid())
+
+	_, err = io.WriteString(proc.Stdin(), "hello\n")
+	require.Nil(t, err)
+	line, err := bufio.NewReader(proc.Stdout()).ReadString('\n')
+	require.Nil(t, err)
+	require.Equal(t, "HELLO\n", line)
+	require.Nil(t, proc.Stdin().Close())
+
+	res, err := proc.Wait()
+	require.Nil(t, err)
+	require.Equal(t, "HELLO\n", res.Stdout.String())
+	// Wait is idempotent and cleans up the source
+	res2, err := proc.Wait()
+	require.Nil(t, err)
+	require.Same(t, res, res2)
+	require.NoFileExists(t, src.Filename)
+}
+
+func TestStartKill(t *testing.T) {
+	pyzero, err := New(&Options{Engines: []string{"python3"}})
+	require.Nil(t, err)
+	src, err := NewSourceWithString(`import time; time.sleep(30)`, "", "")
+	require.Nil(t, err)
+
+	proc, err := pyzero.Start(context.Background(), src, nil)
+	require.Nil(t, err)
+	require.Nil(t, proc.Kill())
+	res, err := proc.Wait()
+	require.NotNil(t, err)
+	require.NotZero(t, res.GetExitCode())
+	require.NoFileExists(t, src.Filename)
+}
+
+func TestEvalTypedErrors(t *testing.T) {
+	pyzero, err := New(&Options{Engines: []string{"python3"}})
+	require.Nil(t, err)
+
+	eval := func(ctx context.Context, code string) (*types.Result, error) {
+		src, err := NewSourceWithString(code, "", "")
+		require.Nil(t, err)
+		defer func() {
+			_ = src.Cleanup()
+		}()
+		input, err := NewSource()
+		require.Nil(t, err)
+		defer func() {
+			_ = input.Cleanup()
+		}()
+		return pyzero.Eval(ctx, src, input)
+	}
+
+	res, err := eval(context.Background(), `import sys; sys.exit(3)`)
+	require.ErrorIs(t, err, types.ErrNonZeroExit)
+	var exitErr *types.NonZeroExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 3, exitErr.Code)
+	require.Equal(t, 3, res.GetExitCode())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = eval(ctx, `import time; time.sleep(10)`)
+	require.ErrorIs(t, err, types.ErrTimeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	if !osutils.IsWindows() {
+		_, err = eval(context.Background(), `import os, signal; os.kill(os.getpid(), signal.SIGTERM)`)
+		require.ErrorIs(t, err, types.ErrKilled)
+		var killedErr *types.KilledError
+		require.ErrorAs(t, err, &killedErr)
+		require.Equal(t, syscall.SIGTERM, killedErr.Signal)
+	}
+}
+
+func TestEvalSuccessCriteria(t *testing.T) {
+	opts := &Options{
+		Engines:         []string{"python3"},
+		SuccessCriteria: &types.SuccessCriteria{ExitCodes: []int{0, 1}, Stderr: types.StderrForbidden},
+	}
+	pyzero, err := New(opts)
+	require.Nil(t, err)
+
+	eval := func(code string) (*types.Result, error) {
+		src, err := NewSourceWithString(code, "", "")
+		require.Nil(t, err)
+		defer func() {
+			_ = src.Cleanup()
+		}()
+		input, err := NewSource()
+		require.Nil(t, err)
+		defer func() {
+			_ = input.Cleanup()
+		}()
+		return pyzero.Eval(context.Background(), src, input)
+	}
+
+	// expected non-zero exit code
+	res, err := eval(`import sys; print("found"); sys.exit(1)`)
+	require.Nil(t, err)
+	require.Equal(t, 1, res.GetExitCode())
+	require.Equal(t, "found\n", res.Stdout.String())
+
+	// unexpected exit code keeps the typed error
+	_, err = eval(`import sys; sys.exit(2)`)
+	require.ErrorIs(t, err, types.ErrNonZeroExit)
+
+	// forbidden stderr output
+	_, err = eval(`import sys; sys.stderr.write("warning")`)
+	require.ErrorIs(t, err, types.ErrUnexpectedOutcome)
+}
+
+func TestEvalRetry(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	opts := &Options{
+		Engines: []string{"python3"},
+		Retry:   &types.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, AllowNonIdempotent: true},
+	}
+	pyzero, err := New(opts)
+	require.Nil(t, err)
+	src, err := NewSourceWithString(`
+import os, sys
+path = os.environ["COUNTER"]
+count = int(open(path).read()) if os.path.exists(path) else 0
+open(path, "w").write(str(count + 1))
+print(sys.stdin.read().strip())
+sys.exit(0 if count >= 1 else 1)
+`, "", "")
+	require.Nil(t, err)
+	defer func() {
+		_ = src.Cleanup()
+	}()
+	src.AddVariable(types.Variable{Name: "COUNTER", Value: counter})
+	input, err := NewSourceWithString("stdin", "", "")
+	require.Nil(t, err)
+	defer func() {
+		_ = input.Cleanup()
+	}()
+
+	res, err := pyzero.Eval(context.Background(), src, input)
+	require.Nil(t, err)
+	require.Len(t, res.Attempts, 2)
+	require.Contains(t, res.Attempts[0].Error, "exit")
+	// stdin is replayed on every attempt
+	require.Equal(t, "stdin\n", res.Stdout.String())
+}
+
+func TestEvalPolicy(t *testing.T) {
+	var reports []*policy.Report
+	p := policy.New(
+		policy.DenyVariables("AWS_*"),
+		policy.When(policy.MetadataEquals("trust", "untrusted"), policy.RequireSandbox()),
+	)
+	p.Reporter = func(r *policy.Report) {
+		reports = append(reports, r)
+	}
+	pyzero, err := New(&Options{Engines: []string{"python3"}, Policy: p})
+	require.Nil(t, err)
+
+	eval := func(metadata map[string]string, vars ...types.Variable) (*types.Result, error) {
+		src, err := NewSourceWithString(`print(1)`, "", "")
+		require.Nil(t, err)
+		defer func() {
+			_ = src.Cleanup()
+		}()
+		src.Metadata = metadata
+		input, err := NewSource()
+		require.Nil(t, err)
+		defer func() {
+			_ = input.Cleanup()
+		}()
+		input.AddVariable(vars...)
+		return pyzero.Eval(context.Background(), src, input)
+	}
+
+	res, err := eval(nil, types.Variable{Name: "NAME", Value: "x"})
+	require.Nil(t, err)
+	require.Equal(t, "1", strings.TrimSpace(res.Stdout.String()))
+
+	res, err = eval(map[string]string{"trust": "untrusted"}, types.Variable{Name: "AWS_TOKEN", Value: "x"})
+	require.Nil(t, res)
+	require.ErrorIs(t, err, types.ErrPolicyViolation)
+	var violationErr *policy.ViolationError
+	require.ErrorAs(t, err, &violationErr)
+	require.Len(t, violationErr.Violations, 2)
+
+	// dry-run only reports the violations
+	p.DryRun = true
+	res, err = eval(map[string]string{"trust": "untrusted"})
+	require.Nil(t, err)
+	require.Equal(t, "1", strings.TrimSpace(res.Stdout.String()))
+	require.Len(t, reports, 3)
+	require.False(t, reports[2].Allowed())
+	require.True(t, reports[2].DryRun)
+}
+
+func TestEvalPreflight(t *testing.T) {
+	pyzero, err := New(&Options{Engines: []string{"python3"}, Preflight: true})
+	require.Nil(t, err)
+	src, err := NewSourceWithString("print(1)\nprint(2\n", "", "")
+	require.Nil(t, err)
+	defer func() {
+		_ = src.Cleanup()
+	}()
+	input, err := NewSource()
+	require.Nil(t, err)
+	defer func() {
+		_ = input.Cleanup()
+	}()
+
+	diagnostics, err := pyzero.Preflight(context.Background(), src)
+	require.Nil(t, err)
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, 2, diagnostics[0].Line)
+
+	res, err := pyzero.Eval(context.Background(), src, input)
+	require.Nil(t, res)
+	require.ErrorIs(t, err, preflight.ErrSyntax)
+	require.NotErrorIs(t, err, types.ErrNonZeroExit)
+}
+
+func TestEvalSandboxUnavailable(t *testing.T) {
+	pyzero, err := New(&Options{Engines: []string{"python3"}, Sandbox: true})
+	require.Nil(t, err)
+	// neither bwrap nor systemd-run can be found
+	t.Setenv("PATH", t.TempDir())
+
+	marker := filepath.Join(t.TempDir(), "marker")
+	newSources := func() (*Source, *Source) {
+		src, err := NewSourceWithString(fmt.Sprintf("open(%q, 'w')", marker), "", "")
+		require.Nil(t, err)
+		input, err := NewSource()
+		require.Nil(t, err)
+		return src, input
+	}
+
+	src, input := newSources()
+	defer func() {
+		_ = src.Cleanup()
+		_ = input.Cleanup()
+	}()
+	res, err := pyzero.Eval(context.Background(), src, input)
+	require.Nil(t, res)
+	require.ErrorIs(t, err, types.ErrBackendUnavailable)
+
+	// sources are left to the caller when Start fails
+	startSrc, startInput := newSources()
+	defer func() {
+		_ = startSrc.Cleanup()
+		_ = startInput.Cleanup()
+	}()
+	_, err = pyzero.Start(context.Background(), startSrc, startInput)
+	require.ErrorIs(t, err, ErrSandboxUnsupported)
+	require.FileExists(t, startSrc.Filename)
+	require.FileExists(t, startInput.Filename)
+
+	// the source never ran unsandboxed
+	require.NoFileExists(t, marker)
+}
+
+func TestEvalWorkDir(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.Nil(t, err)
+	pyzero, err := New(&Options{Engines: []string{"python3"}, WorkDir: dir})
+	require.Nil(t, err)
+	src, err := NewSourceWithString("import os\nprint(os.getcwd())", "", "")
+	require.Nil(t, err)
+	input, err := NewSource()
+	require.Nil(t, err)
+	defer func() {
+		_ = src.Cleanup()
+		_ = input.Cleanup()
+	}()
+	res, err := pyzero.Eval(context.Background(), src, input)
+	require.Nil(t, err)
+	require.Equal(t, dir, strings.TrimSpace(res.Stdout.String()))
+}
+
+func TestEvalSandboxUnsupported(t *testing.T) {
+	if osutils.IsWindows() {
+		t.Skip("requires a unix sandbox")
+	}
+	pyzero, err := New(&Options{Engines: []string{"python3"}, Sandbox: true})
+	require.Nil(t, err)
+	// a fake bwrap selects the bubblewrap backend, it never runs
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "bwrap"), []byte("#!/bin/sh\nexit 1\n"), 0755))
+	t.Setenv("PATH", dir)
+
+	src, err := NewSourceWithString("print(1)", "", "")
+	require.Nil(t, err)
+	input, err := NewSource()
+	require.Nil(t, err)
+	defer func() {
+		_ = src.Cleanup()
+		_ = input.Cleanup()
+	}()
+
+	// options which would be silently ignored by the backend are rejected
+	pyzero.Options.Credential = &cmdexec.Credential{Uid: 65534, Gid: 65534}
+	_, err = pyzero.Eval(context.Background(), src, input)
+	require.ErrorIs(t, err, ErrSandboxUnsupported)
+	pyzero.Options.Credential = nil
+	pyzero.Options.WorkDir = dir
+	_, err = pyzero.Eval(context.Background(), src, input)
+	require.ErrorIs(t, err, ErrSandboxUnsupported)
+	pyzero.Options.WorkDir = ""
+	pyzero.Options.Transcript = true
+	_, err = pyzero.Eval(context.Background(), src, input)
+	require.ErrorIs(t, err, ErrSandboxUnsupported)
+	pyzero.Options.Transcript = false
+	pyzero.Options.PreferStartProcess = true
+	_, err = pyzero.Eval(context.Background(), src, input)
+	require.ErrorIs(t, err, ErrSandboxUnsupported)
+}
+
+func TestEvalSandboxCleanEnv(t *testing.T) {
+	if !osutils.IsLinux() {
+		t.Skip("requires bubblewrap")
+	}
+	pyzero, err := New(&Options{Engines: []string{"python3"}, Sandbox: true, CleanEnv: true})
+	require.Nil(t, err)
+	// the fake bwrap only implements the environment handling of bwrap
+	dir := t.TempDir()
+	fake := "#!/bin/sh\n" +
+		"case \" $* \" in *\" --clearenv \"*) unset GOZERO_HOST_VARIABLE;; esac\n" +
+		"echo \"[$GOZERO_HOST_VARIABLE]\"\n"
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "bwrap"), []byte(fake), 0755))
+	t.Setenv("PATH", dir)
+	t.Setenv("GOZERO_HOST_VARIABLE", "leaked")
+
+	src, err := NewSourceWithString("print(1)", "", "")
+	require.Nil(t, err)
+	input, err := NewSource()
+	require.Nil(t, err)
+	defer func() {
+		_ = src.Cleanup()
+		_ = input.Cleanup()
+	}()
+
+	// the host environment is not visible in the sandbox
+	res, err := pyzero.Eval(context.Background(), src, input)
+	require.Nil(t, err)
+	require.Equal(t, "[]", strings.TrimSpace(res.Stdout.String()))
+}
+
+func TestEvalTranscript(t *testing.T) {
+	pyzero, err := New(&Options{Engines: []string{"python3"}, Transcript: true})
+	require.Nil(t, err)
+	src, err := NewSourceWithString("import sys, time\nprint('out', flush=True)\ntime.sleep(0.1)\nprint('err', file=sys.stderr, flush=True)", "", "")
+	require.Nil(t, err)
+	input, err := NewSource()
+	require.Nil(t, err)
+	defer func() {
+		_ = src.Cleanup()
+		_ = input.Cleanup()
+	}()
+	res, err := pyzero.Eval(context.Background(), src, input)
+	require.Nil(t, err)
+	require.NotNil(t, res.Transcript)
+	// python may write a line in several chunks
+	require.Regexp(t, `^\[ +[\d.]+s\] stdout \| out\n\[ +[\d.]+s\] stderr \| err\n$`, res.Transcript.String())
+}
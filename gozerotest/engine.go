@@ -0,0 +1,58 @@
+// gozerotest package provides helpers to test code using gozero without
+// real interpreters, docker or bubblewrap: a scriptable fake engine,
+// an in-memory sandbox and golden-file assertions of results.
+package gozerotest
+
+import (
+	_ "embed"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeEngineSource is the program of the fake engine, built with the go
+// toolchain in a standalone module so it does not depend on the caller's module
+//
+//go:embed fakeengine/main.go
+var fakeEngineSource []byte
+
+// FakeEngine builds the fake engine in a temporary directory and returns
+// its path, usable as engine of gozero.Options. Sources run by the engine
+// are scripts of directives (see fakeengine/main.go):
+//
+//	stdout hello
+//	stderr warning
+//	exit 3
+//
+// The test is skipped when the go toolchain is not installed
+func FakeEngine(t testing.TB) string {
+	t.Helper()
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("go toolchain not found: %v", err)
+	}
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"go.mod":  []byte("module fakeengine\n\ngo 1.21\n"),
+		"main.go": fakeEngineSource,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o600); err != nil {
+			t.Fatalf("could not write fake engine: %v", err)
+		}
+	}
+	binary := filepath.Join(dir, "fakeengine")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	cmd := exec.Command(goBin, "build", "-o", binary, ".")
+	cmd.Dir = dir
+	// the settings of the caller's module do not apply to the engine module
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "CGO_ENABLED=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("could not build fake engine: %v\n%s", err, out)
+	}
+	return binary
+}
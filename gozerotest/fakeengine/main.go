@@ -0,0 +1,85 @@
+// fakeengine is a scriptable engine built by gozerotest.FakeEngine.
+// It runs the source passed as first argument, one directive per line:
+//
+//	stdout <text>   writes text and a newline to stdout
+//	stderr <text>   writes text and a newline to stderr
+//	stdin           copies stdin to stdout
+//	env <name>      writes the value of the environment variable to stdout
+//	args            writes the arguments following the source, one per line
+//	sleep <d>       sleeps for the duration (e.g. 100ms)
+//	exit <code>     exits with code
+//
+// Empty lines and lines starting with # are ignored
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: fakeengine <source> [args...]")
+		os.Exit(2)
+	}
+	source, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(source)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(strings.TrimSpace(text), "#") {
+			continue
+		}
+		directive, arg, _ := strings.Cut(strings.TrimLeft(text, " \t"), " ")
+		if err := run(directive, arg); err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%d: %v\n", os.Args[1], line, err)
+			os.Exit(2)
+		}
+	}
+}
+
+func run(directive, arg string) error {
+	switch directive {
+	case "stdout":
+		_, err := fmt.Fprintln(os.Stdout, arg)
+		return err
+	case "stderr":
+		_, err := fmt.Fprintln(os.Stderr, arg)
+		return err
+	case "stdin":
+		_, err := io.Copy(os.Stdout, os.Stdin)
+		return err
+	case "env":
+		_, err := fmt.Fprintln(os.Stdout, os.Getenv(arg))
+		return err
+	case "args":
+		for _, a := range os.Args[2:] {
+			if _, err := fmt.Fprintln(os.Stdout, a); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "sleep":
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return err
+		}
+		time.Sleep(d)
+		return nil
+	case "exit":
+		code, err := strconv.Atoi(arg)
+		if err != nil {
+			return err
+		}
+		os.Exit(code)
+	}
+	return fmt.Errorf("unknown directive %q", directive)
+}
@@ -0,0 +1,70 @@
+package gozerotest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/stretchr/testify/require"
+)
+
+// update rewrites golden files instead of comparing them. The flag is
+// namespaced to not conflict with -update flags of the caller's tests
+var update = flag.Bool("gozerotest.update", false, "update gozerotest golden files")
+
+// UpdateEnv set to a non-empty value also updates golden files
+const UpdateEnv = "GOZEROTEST_UPDATE"
+
+// GoldenDir is the directory of golden files, relative to the package under test
+var GoldenDir = "testdata"
+
+// AssertGolden compares the exit code, stdout and stderr of res with the
+// golden file GoldenDir/<name>.golden. Golden files are written instead
+// when tests run with -gozerotest.update or GOZEROTEST_UPDATE=1
+func AssertGolden(t testing.TB, name string, res *types.Result) {
+	t.Helper()
+	require.NotNil(t, res, "nil result")
+	got := FormatResult(res)
+	path := filepath.Join(GoldenDir, filepath.FromSlash(name)+".golden")
+
+	if *update || os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("could not create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("could not update golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("golden file %s does not exist, run the tests with -gozerotest.update to create it", path)
+	}
+	require.Nil(t, err)
+	require.Equal(t, string(want), string(got), "result does not match golden file %s", path)
+}
+
+// FormatResult returns the golden representation of res
+func FormatResult(res *types.Result) []byte {
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "-- exit code --\n%d\n", res.GetExitCode())
+	writeSection(&buf, "stdout", res.Stdout.Bytes())
+	writeSection(&buf, "stderr", res.Stderr.Bytes())
+	return buf.Bytes()
+}
+
+// writeSection writes the header and data of a section. Data not ending
+// with a newline is marked so it is not confused with data ending with one
+func writeSection(buf *bytes.Buffer, name string, data []byte) {
+	_, _ = fmt.Fprintf(buf, "-- %s --\n", name)
+	buf.Write(data)
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		buf.WriteString("\n-- no newline at end of " + name + " --\n")
+	}
+}
@@ -0,0 +1,105 @@
+package gozerotest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/gozero"
+	"github.com/projectdiscovery/gozero/gozerotest"
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeEngine(t *testing.T) {
+	engine := gozerotest.FakeEngine(t)
+	g, err := gozero.New(&gozero.Options{Engines: []string{engine}})
+	require.Nil(t, err)
+
+	src, err := gozero.NewSourceWithString(strings.Join([]string{
+		"# scripted engine",
+		"stdout hello",
+		"env GOZERO_VAR",
+		"stdin",
+		"args",
+		"stderr warning",
+		"exit 3",
+		"stdout unreachable",
+	}, "\n"), "", "")
+	require.Nil(t, err)
+	defer func() {
+		_ = src.Cleanup()
+	}()
+	src.AddVariable(types.Variable{Name: "GOZERO_VAR", Value: "value"})
+	input, err := gozero.NewSourceWithString("input\n", "", "")
+	require.Nil(t, err)
+	defer func() {
+		_ = input.Cleanup()
+	}()
+
+	res, err := g.Eval(context.Background(), src, input, "first", "second")
+	require.ErrorIs(t, err, types.ErrNonZeroExit)
+	gozerotest.AssertGolden(t, "fake_engine", res)
+
+	sleep, err := gozero.NewSourceWithString("sleep 10s", "", "")
+	require.Nil(t, err)
+	defer func() {
+		_ = sleep.Cleanup()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	empty, err := gozero.NewSource()
+	require.Nil(t, err)
+	defer func() {
+		_ = empty.Cleanup()
+	}()
+	_, err = g.Eval(ctx, sleep, empty)
+	require.ErrorIs(t, err, types.ErrTimeout)
+}
+
+func TestSandbox(t *testing.T) {
+	sb := gozerotest.NewSandbox(
+		gozerotest.Response{Result: gozerotest.NewResult("first\n", "", 0)},
+		gozerotest.ExitResponse("", "failed\n", 2),
+	)
+	require.Nil(t, sb.Start())
+
+	res, err := sb.Run(context.Background(), "echo first")
+	require.Nil(t, err)
+	require.Equal(t, "first\n", res.Stdout.String())
+
+	res, err = sb.RunSource(context.Background(), "print(1)", "python3")
+	require.ErrorIs(t, err, types.ErrNonZeroExit)
+	require.Equal(t, 2, res.GetExitCode())
+	gozerotest.AssertGolden(t, "sandbox/exit", res)
+
+	// the last response is repeated
+	_, err = sb.RunScript(context.Background(), "exit 2")
+	require.ErrorIs(t, err, types.ErrNonZeroExit)
+	_, err = sb.RunArgs(context.Background(), []string{"python3", "-c", "print(1 + 2)"})
+	require.ErrorIs(t, err, types.ErrNonZeroExit)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = sb.Run(ctx, "true")
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, sb.Stop())
+
+	require.Equal(t, []gozerotest.Call{
+		{Method: gozerotest.MethodStart},
+		{Method: gozerotest.MethodRun, Command: "echo first"},
+		{Method: gozerotest.MethodRunSource, Command: "print(1)", Interpreter: "python3"},
+		{Method: gozerotest.MethodRunScript, Command: "exit 2"},
+		{Method: gozerotest.MethodRunArgs, Args: []string{"python3", "-c", "print(1 + 2)"}},
+		{Method: gozerotest.MethodRun, Command: "true"},
+		{Method: gozerotest.MethodStop},
+	}, sb.Calls())
+	sb.Reset()
+	require.Empty(t, sb.Calls())
+}
+
+func TestFormatResult(t *testing.T) {
+	require.Equal(t, "-- exit code --\n1\n-- stdout --\nout\n-- stderr --\nerr\n-- no newline at end of stderr --\n",
+		string(gozerotest.FormatResult(gozerotest.NewResult("out\n", "err", 1))))
+}
@@ -0,0 +1,137 @@
+package gozerotest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/projectdiscovery/gozero/sandbox"
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// Sandbox methods recorded in calls
+const (
+	MethodRun       = "Run"
+	MethodRunArgs   = "RunArgs"
+	MethodRunScript = "RunScript"
+	MethodRunSource = "RunSource"
+	MethodStart     = "Start"
+	MethodWait      = "Wait"
+	MethodStop      = "Stop"
+	MethodClear     = "Clear"
+)
+
+// Call is a call made to a Sandbox
+type Call struct {
+	Method string
+	// Command of Run, source of RunScript and RunSource
+	Command     string
+	Args        []string // args of RunArgs
+	Interpreter string
+}
+
+// Response is a canned response of a Sandbox
+type Response struct {
+	Result *types.Result
+	Err    error
+}
+
+// Sandbox is an in-memory sandbox.Sandbox recording its calls and returning
+// canned responses. It is safe for concurrent use
+type Sandbox struct {
+	mu        sync.Mutex
+	calls     []Call
+	responses []Response
+}
+
+var _ sandbox.Sandbox = (*Sandbox)(nil)
+
+// NewSandbox returns a sandbox returning responses in order to the Run
+// methods. The last response is repeated, an empty result is returned when
+// there are none
+func NewSandbox(responses ...Response) *Sandbox {
+	return &Sandbox{responses: responses}
+}
+
+// Return queues responses after the pending ones
+func (s *Sandbox) Return(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, responses...)
+}
+
+// Calls returns the calls made so far
+func (s *Sandbox) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call{}, s.calls...)
+}
+
+// Reset forgets the calls made so far
+func (s *Sandbox) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+func (s *Sandbox) Run(ctx context.Context, cmd string) (*types.Result, error) {
+	return s.run(ctx, Call{Method: MethodRun, Command: cmd})
+}
+
+func (s *Sandbox) RunArgs(ctx context.Context, args []string) (*types.Result, error) {
+	return s.run(ctx, Call{Method: MethodRunArgs, Args: append([]string{}, args...)})
+}
+
+func (s *Sandbox) RunScript(ctx context.Context, source string) (*types.Result, error) {
+	return s.run(ctx, Call{Method: MethodRunScript, Command: source})
+}
+
+func (s *Sandbox) RunSource(ctx context.Context, source string, interpreter string) (*types.Result, error) {
+	return s.run(ctx, Call{Method: MethodRunSource, Command: source, Interpreter: interpreter})
+}
+
+func (s *Sandbox) Start() error { return s.record(Call{Method: MethodStart}) }
+func (s *Sandbox) Wait() error  { return s.record(Call{Method: MethodWait}) }
+func (s *Sandbox) Stop() error  { return s.record(Call{Method: MethodStop}) }
+func (s *Sandbox) Clear() error { return s.record(Call{Method: MethodClear}) }
+
+func (s *Sandbox) record(call Call) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, call)
+	return nil
+}
+
+// run records call and returns the next response. Canceled contexts
+// are reported like the real backends
+func (s *Sandbox) run(ctx context.Context, call Call) (*types.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, call)
+	if err := ctx.Err(); err != nil {
+		return nil, types.WaitError(ctx, err)
+	}
+	if len(s.responses) == 0 {
+		return &types.Result{Command: call.Command}, nil
+	}
+	response := s.responses[0]
+	if len(s.responses) > 1 {
+		s.responses = s.responses[1:]
+	}
+	return response.Result, response.Err
+}
+
+// NewResult returns a result with the given output and exit code,
+// to be used as canned response
+func NewResult(stdout, stderr string, exitCode int) *types.Result {
+	res := &types.Result{}
+	res.Stdout.WriteString(stdout)
+	res.Stderr.WriteString(stderr)
+	res.SetExitCode(exitCode)
+	return res
+}
+
+// ExitResponse returns the response of an execution exiting with exitCode,
+// with the typed error a real backend returns (see types.ExitCodeError)
+func ExitResponse(stdout, stderr string, exitCode int) Response {
+	return Response{Result: NewResult(stdout, stderr, exitCode), Err: types.ExitCodeError(exitCode)}
+}
@@ -0,0 +1,123 @@
+//go:build linux
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/projectdiscovery/gozero/sandbox"
+	"github.com/projectdiscovery/gozero/types"
+)
+
+func runBubblewrap(ctx context.Context, spec *Spec) (*types.Result, error) {
+	engine, err := LookEngine(spec.engines())
+	if err != nil {
+		return nil, err
+	}
+	bwrap, err := sandbox.NewBubblewrapSandbox(ctx, &sandbox.BubblewrapConfiguration{
+		HostFilesystem: true,
+		NewSession:     true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// only the script is made visible inside the sandbox
+	scriptDir, err := stageScript(os.TempDir(), spec)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(scriptDir)
+	}()
+
+	options := &sandbox.BubblewrapCommandOptions{
+		Command:      engine,
+		Engine:       engine,
+		Args:         append([]string{"/src/script"}, spec.Args...),
+		CommandBinds: []sandbox.BindMount{{HostPath: scriptDir, SandboxPath: "/src"}},
+		Chdir:        "/src",
+		Environment:  spec.environment(),
+		Stdin:        string(spec.Stdin),
+	}
+	return bwrap.ExecuteWithOptions(ctx, options)
+}
+
+func runSystemd(ctx context.Context, spec *Spec) (*types.Result, error) {
+	engine, err := LookEngine(spec.engines())
+	if err != nil {
+		return nil, err
+	}
+	// the private /tmp of the unit hides the host one, the script is staged
+	// in the user cache directory instead
+	dir, err := systemdScriptDir()
+	if err != nil {
+		return nil, err
+	}
+	scriptDir, err := stageScript(dir, spec)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(scriptDir)
+	}()
+	script := filepath.Join(scriptDir, "script")
+	config := &sandbox.Configuration{
+		Rules: []sandbox.Rule{
+			{Filter: sandbox.NoNewPrivileges, Arg: sandbox.Arg{Type: sandbox.Bool, Params: "yes"}},
+			{Filter: sandbox.PrivateTmp, Arg: sandbox.Arg{Type: sandbox.Bool, Params: "yes"}},
+		},
+		Environment: spec.environment(),
+	}
+	if spec.Limits.Network == "none" {
+		config.Rules = append(config.Rules, sandbox.Rule{Filter: sandbox.PrivateNetwork, Arg: sandbox.Arg{Type: sandbox.Bool, Params: "yes"}})
+	}
+	if spec.Limits.Memory != "" {
+		config.Rules = append(config.Rules, sandbox.Rule{Filter: sandbox.MemoryMax, Arg: sandbox.Arg{Type: sandbox.Value, Params: strings.ToUpper(spec.Limits.Memory)}})
+	}
+	if spec.Limits.CPUs != "" {
+		cpus, err := strconv.ParseFloat(spec.Limits.CPUs, 64)
+		if err != nil || cpus <= 0 {
+			return nil, fmt.Errorf("invalid cpu limit %q", spec.Limits.CPUs)
+		}
+		quota := strconv.FormatFloat(cpus*100, 'f', -1, 64) + "%"
+		config.Rules = append(config.Rules, sandbox.Rule{Filter: sandbox.CPUQuota, Arg: sandbox.Arg{Type: sandbox.Value, Params: quota}})
+	}
+	systemd, err := sandbox.New(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return systemd.RunArgs(ctx, append([]string{engine, script}, spec.Args...))
+}
+
+// systemdScriptDir returns the directory scripts run by systemd units are staged in
+func systemdScriptDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cacheDir, "gozero")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// stageScript writes the source of spec as "script" in a new directory in dir
+// and returns the directory
+func stageScript(dir string, spec *Spec) (string, error) {
+	scriptDir, err := os.MkdirTemp(dir, "gozero-script-*")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(scriptDir, "script"), spec.Source, 0644); err != nil {
+		_ = os.RemoveAll(scriptDir)
+		return "", err
+	}
+	return scriptDir, nil
+}
@@ -0,0 +1,45 @@
+//go:build linux
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemdScriptDir(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	dir, err := systemdScriptDir()
+	require.NoError(t, err)
+	scriptDir, err := stageScript(dir, &Spec{Source: []byte("echo staged")})
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(scriptDir)
+	}()
+
+	// the private /tmp of systemd units hides scripts staged in the host /tmp
+	data, err := os.ReadFile(filepath.Join(scriptDir, "script"))
+	require.NoError(t, err)
+	require.Equal(t, "echo staged", string(data))
+	require.Equal(t, filepath.Join(cacheDir, "gozero"), filepath.Dir(scriptDir))
+}
+
+func TestRunSystemd(t *testing.T) {
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		t.Skip("systemd is not running")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var stdout bytes.Buffer
+	_, err := Run(ctx, &Spec{Language: "sh", Source: []byte("echo systemd"), Backend: BackendSystemd, Stdout: &stdout})
+	require.NoError(t, err)
+	require.Equal(t, "systemd", strings.TrimSpace(stdout.String()))
+}
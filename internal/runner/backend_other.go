@@ -0,0 +1,18 @@
+//go:build !linux
+
+package runner
+
+import (
+	"context"
+	"errors"
+
+	"github.com/projectdiscovery/gozero/types"
+)
+
+func runBubblewrap(context.Context, *Spec) (*types.Result, error) {
+	return nil, types.BackendUnavailableError(errors.New("bubblewrap is only available on linux"))
+}
+
+func runSystemd(context.Context, *Spec) (*types.Result, error) {
+	return nil, types.BackendUnavailableError(errors.New("systemd sandbox is only available on linux"))
+}
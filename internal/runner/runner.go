@@ -0,0 +1,292 @@
+// runner package runs a script with an engine on a selectable backend.
+// It is shared by the gozero command-line tool and the execution server.
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gozero"
+	"github.com/projectdiscovery/gozero/sandbox"
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// Backends a script can run on
+const (
+	BackendNone       = "none"
+	BackendDocker     = "docker"
+	BackendBubblewrap = "bwrap"
+	BackendSystemd    = "systemd"
+)
+
+// Backends lists the supported backends
+var Backends = []string{BackendNone, BackendDocker, BackendBubblewrap, BackendSystemd}
+
+// Language describes the engines and default docker image of a language
+type Language struct {
+	Engines []string
+	Image   string
+}
+
+// Languages are the languages which can be used instead of explicit engines
+var Languages = map[string]Language{
+	"python":     {Engines: []string{"python3", "python"}, Image: "python:3-alpine"},
+	"node":       {Engines: []string{"node"}, Image: "node:alpine"},
+	"javascript": {Engines: []string{"node"}, Image: "node:alpine"},
+	"bash":       {Engines: []string{"bash"}, Image: "bash:latest"},
+	"sh":         {Engines: []string{"sh"}, Image: "alpine:latest"},
+	"ruby":       {Engines: []string{"ruby"}, Image: "ruby:alpine"},
+	"php":        {Engines: []string{"php"}, Image: "php:cli-alpine"},
+	"perl":       {Engines: []string{"perl"}, Image: "perl:slim"},
+}
+
+// LanguageNames returns the sorted names of the supported languages
+func LanguageNames() []string {
+	names := make([]string, 0, len(Languages))
+	for name := range Languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Limits are the resource limits of a sandboxed run
+type Limits struct {
+	Image   string // docker image (defaults to the image of the language)
+	Memory  string // memory limit (e.g. 512m) for docker and systemd
+	CPUs    string // cpu limit (e.g. 0.5) for docker and systemd
+	Network string // none disables networking, docker also accepts a network mode
+}
+
+// Spec describes a script to run
+type Spec struct {
+	// Language of the script, used when Engines is empty
+	Language string
+	// Engines to try in order
+	Engines   []string
+	Source    []byte
+	Stdin     []byte // nil when the script has no input
+	Variables []types.Variable
+	Args      []string
+	Backend   string
+	Limits    Limits
+
+	// Stdout and Stderr (optional) receive the output of the script. Local runs
+	// stream it while the script runs, sandboxed runs write it once it exited
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Validate checks that the spec is supported by its backend
+func (s *Spec) Validate() error {
+	if len(s.Engines) == 0 && s.Language == "" {
+		return errors.New("an engine or a language is required")
+	}
+	if s.Language != "" {
+		if _, ok := Languages[s.Language]; !ok {
+			return fmt.Errorf("unknown language %q (supported: %s)", s.Language, strings.Join(LanguageNames(), ", "))
+		}
+	}
+	limits := s.Limits
+	switch s.Backend {
+	case BackendNone, "":
+		if limits != (Limits{}) {
+			return errors.New("image, memory, cpus and network limits require a sandbox")
+		}
+	case BackendDocker:
+		if s.Stdin != nil {
+			return errors.New("input is not supported by the docker sandbox")
+		}
+		if len(s.Args) > 0 {
+			return errors.New("script arguments are not supported by the docker sandbox")
+		}
+		if limits.Image == "" && s.Language == "" {
+			return errors.New("an image is required when the language is not set")
+		}
+	case BackendBubblewrap:
+		if limits.Image != "" || limits.Memory != "" || limits.CPUs != "" {
+			return errors.New("image, memory and cpus limits are not supported by the bwrap sandbox")
+		}
+		if limits.Network != "" && limits.Network != "none" {
+			return errors.New("the bwrap sandbox has no network access (network none)")
+		}
+	case BackendSystemd:
+		if limits.Image != "" {
+			return errors.New("image is not supported by the systemd sandbox")
+		}
+		if s.Stdin != nil {
+			return errors.New("input is not supported by the systemd sandbox")
+		}
+		if limits.Network != "" && limits.Network != "none" {
+			return errors.New("the systemd sandbox only supports network none")
+		}
+	default:
+		return fmt.Errorf("unknown sandbox %q (supported: %s)", s.Backend, strings.Join(Backends, ", "))
+	}
+	return nil
+}
+
+// engines returns the engines to try in order
+func (s *Spec) engines() []string {
+	if len(s.Engines) > 0 {
+		return s.Engines
+	}
+	return Languages[s.Language].Engines
+}
+
+// LookEngine returns the path of the first of engines installed on the
+// host, like gozero.New does for local runs
+func LookEngine(engines []string) (string, error) {
+	for _, engine := range engines {
+		if path, err := exec.LookPath(engine); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", gozero.ErrNoValidEngine, strings.Join(engines, ", "))
+}
+
+// ContainerInterpreter returns the shell word running the first of engines
+// installed in a container, which cannot be looked up from the host
+func ContainerInterpreter(engines []string) string {
+	if len(engines) == 1 {
+		return engines[0]
+	}
+	lookups := make([]string, 0, len(engines)+1)
+	for _, engine := range engines {
+		lookups = append(lookups, "command -v "+ShellQuote(engine))
+	}
+	// a missing engine is reported by the shell
+	lookups = append(lookups, "echo "+ShellQuote(engines[0]))
+	return `"$(` + strings.Join(lookups, " || ") + `)"`
+}
+
+// ShellQuote quotes s as a single shell word
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// environment returns the variables as a map
+func (s *Spec) environment() map[string]string {
+	env := make(map[string]string, len(s.Variables))
+	for _, variable := range s.Variables {
+		env[variable.Name] = variable.Value
+	}
+	return env
+}
+
+// Run validates and runs the spec on its backend
+func Run(ctx context.Context, spec *Spec) (*types.Result, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	var res *types.Result
+	var err error
+	switch spec.Backend {
+	case BackendDocker:
+		res, err = runDocker(ctx, spec)
+	case BackendBubblewrap:
+		res, err = runBubblewrap(ctx, spec)
+	case BackendSystemd:
+		res, err = runSystemd(ctx, spec)
+	default:
+		// local runs stream their output
+		return runLocal(ctx, spec)
+	}
+	if res != nil {
+		if writeErr := writeOutput(spec, res); writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+	return res, err
+}
+
+func runLocal(ctx context.Context, spec *Spec) (*types.Result, error) {
+	g, err := gozero.New(&gozero.Options{Engines: spec.engines()})
+	if err != nil {
+		return nil, err
+	}
+	src, err := gozero.NewSourceWithBytes(spec.Source, "gozero-*", "")
+	if err != nil {
+		return nil, err
+	}
+	input := &gozero.Source{Variables: spec.Variables}
+	// src is cleaned up by Wait once started
+	proc, err := g.Start(ctx, src, input, spec.Args...)
+	if err != nil {
+		_ = src.Cleanup()
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	for _, stream := range []struct {
+		w io.Writer
+		r io.Reader
+	}{{spec.Stdout, proc.Stdout()}, {spec.Stderr, proc.Stderr()}} {
+		if stream.w == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = io.Copy(stream.w, stream.r)
+		}()
+	}
+	go func() {
+		if len(spec.Stdin) > 0 {
+			_, _ = proc.Stdin().Write(spec.Stdin)
+		}
+		_ = proc.Stdin().Close()
+	}()
+
+	res, err := proc.Wait()
+	wg.Wait()
+	return res, err
+}
+
+func runDocker(ctx context.Context, spec *Spec) (*types.Result, error) {
+	config := &sandbox.DockerConfiguration{
+		Image:           spec.Limits.Image,
+		WorkingDir:      "/tmp",
+		Environment:     spec.environment(),
+		Memory:          spec.Limits.Memory,
+		CPULimit:        spec.Limits.CPUs,
+		NetworkDisabled: spec.Limits.Network == "none",
+		Remove:          true,
+	}
+	if config.Image == "" {
+		config.Image = Languages[spec.Language].Image
+	}
+	if spec.Limits.Network != "none" {
+		config.NetworkMode = spec.Limits.Network
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		config.Timeout = time.Until(deadline)
+	}
+	docker, err := sandbox.NewDockerSandbox(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return docker.RunSource(ctx, string(spec.Source), ContainerInterpreter(spec.engines()))
+}
+
+// writeOutput writes the output of a sandboxed run to the spec writers
+func writeOutput(spec *Spec, res *types.Result) error {
+	if spec.Stdout != nil {
+		if _, err := spec.Stdout.Write(res.Stdout.Bytes()); err != nil {
+			return err
+		}
+	}
+	if spec.Stderr != nil {
+		if _, err := spec.Stderr.Write(res.Stderr.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
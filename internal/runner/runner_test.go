@@ -0,0 +1,36 @@
+package runner
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/projectdiscovery/gozero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookEngine(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not installed")
+	}
+	// the first installed engine is used, like local runs
+	engine, err := LookEngine([]string{"missing-engine", "sh"})
+	require.NoError(t, err)
+	require.Equal(t, sh, engine)
+
+	_, err = LookEngine([]string{"missing-engine"})
+	require.ErrorIs(t, err, gozero.ErrNoValidEngine)
+}
+
+func TestContainerInterpreter(t *testing.T) {
+	require.Equal(t, "python3", ContainerInterpreter([]string{"python3"}))
+	require.Equal(t, `"$(command -v 'python3' || command -v 'python' || echo 'python3')"`, ContainerInterpreter([]string{"python3", "python"}))
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not installed")
+	}
+	out, err := exec.Command(sh, "-c", "exec "+ContainerInterpreter([]string{"missing-engine", "sh"})+" -c 'echo found'").Output()
+	require.NoError(t, err)
+	require.Equal(t, "found\n", string(out))
+}
@@ -0,0 +1,60 @@
+// telemetry package contains shared helpers used to instrument gozero with OpenTelemetry.
+package telemetry
+
+import (
+	"github.com/projectdiscovery/gozero/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the tracer used by gozero
+const InstrumentationName = "github.com/projectdiscovery/gozero"
+
+// Attribute keys set on gozero spans
+const (
+	AttrEngine      = attribute.Key("gozero.engine")
+	AttrBackend     = attribute.Key("gozero.backend")
+	AttrExitCode    = attribute.Key("gozero.exit_code")
+	AttrStdoutBytes = attribute.Key("gozero.stdout.bytes")
+	AttrStderrBytes = attribute.Key("gozero.stderr.bytes")
+)
+
+// Backend names used in the backend attribute
+const (
+	BackendLocal      = "local"
+	BackendDocker     = "docker"
+	BackendBubblewrap = "bubblewrap"
+	BackendSystemd    = "systemd"
+)
+
+// Tracer returns the gozero tracer from the given provider,
+// falling back to the global provider when tp is nil
+func Tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(InstrumentationName)
+}
+
+// End records the outcome of an execution on the span and ends it
+func End(span trace.Span, res *types.Result, err error) {
+	if res != nil {
+		span.SetAttributes(
+			AttrExitCode.Int(res.GetExitCode()),
+			AttrStdoutBytes.Int(res.Stdout.Len()),
+			AttrStderrBytes.Int(res.Stderr.Len()),
+		)
+	}
+	EndErr(span, err)
+}
+
+// EndErr records err (if any) on the span and ends it
+func EndErr(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"expvar"
+	"strings"
+	"time"
+)
+
+// ExpvarSink is a Sink which publishes metrics through the expvar package.
+// Each metric is an expvar.Map keyed by the joined label values (engine/backend/outcome)
+type ExpvarSink struct {
+	root              *expvar.Map
+	executions        *expvar.Map
+	durationSeconds   *expvar.Map
+	inFlight          *expvar.Map
+	stdoutBytes       *expvar.Map
+	stderrBytes       *expvar.Map
+	containersCreated *expvar.Map
+	containersRemoved *expvar.Map
+}
+
+// NewExpvar creates an expvar sink published under name.
+// If a map with the same name is already published it is reused
+func NewExpvar(name string) *ExpvarSink {
+	if name == "" {
+		name = "gozero"
+	}
+	root, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		root = expvar.NewMap(name)
+	}
+	e := &ExpvarSink{root: root}
+	e.executions = e.child("executions_total")
+	e.durationSeconds = e.child("execution_duration_seconds_sum")
+	e.inFlight = e.child("executions_in_flight")
+	e.stdoutBytes = e.child("stdout_bytes_total")
+	e.stderrBytes = e.child("stderr_bytes_total")
+	e.containersCreated = e.child("sandbox_containers_created_total")
+	e.containersRemoved = e.child("sandbox_containers_removed_total")
+	return e
+}
+
+// Map returns the published root map
+func (e *ExpvarSink) Map() *expvar.Map {
+	return e.root
+}
+
+func (e *ExpvarSink) child(name string) *expvar.Map {
+	if m, ok := e.root.Get(name).(*expvar.Map); ok {
+		return m
+	}
+	m := new(expvar.Map).Init()
+	e.root.Set(name, m)
+	return m
+}
+
+// ExecutionStarted implements Sink
+func (e *ExpvarSink) ExecutionStarted(labels Labels) {
+	e.inFlight.Add(labelKey(labels.Engine, labels.Backend), 1)
+}
+
+// ExecutionFinished implements Sink
+func (e *ExpvarSink) ExecutionFinished(labels Labels, duration time.Duration, stdoutBytes, stderrBytes int) {
+	key := labelKey(labels.Engine, labels.Backend)
+	keyWithOutcome := labelKey(labels.Engine, labels.Backend, labels.Outcome)
+	e.inFlight.Add(key, -1)
+	e.executions.Add(keyWithOutcome, 1)
+	e.durationSeconds.AddFloat(keyWithOutcome, duration.Seconds())
+	e.stdoutBytes.Add(key, int64(stdoutBytes))
+	e.stderrBytes.Add(key, int64(stderrBytes))
+}
+
+// ContainerCreated implements Sink
+func (e *ExpvarSink) ContainerCreated(backend string) {
+	e.containersCreated.Add(backend, 1)
+}
+
+// ContainerRemoved implements Sink
+func (e *ExpvarSink) ContainerRemoved(backend string) {
+	e.containersRemoved.Add(backend, 1)
+}
+
+func labelKey(values ...string) string {
+	return strings.Join(values, "/")
+}
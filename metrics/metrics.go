@@ -0,0 +1,130 @@
+// metrics package defines the metrics sink gozero and the sandbox backends emit to.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// Outcome values used in the outcome label
+const (
+	OutcomeSuccess            = "success"
+	OutcomeNonZeroExit        = "non_zero_exit"
+	OutcomeTimeout            = "timeout"
+	OutcomeCanceled           = "canceled"
+	OutcomeKilled             = "killed"
+	OutcomeOOM                = "oom"
+	OutcomeStartFailed        = "start_failed"
+	OutcomeBackendUnavailable = "backend_unavailable"
+	OutcomePolicyViolation    = "policy_violation"
+	OutcomeError              = "error"
+)
+
+// EngineCommand is the engine label of ad-hoc commands, which are not
+// run by a configured engine
+const EngineCommand = "command"
+
+// Labels is the bounded label set attached to execution metrics.
+// Outcome is empty for metrics reported before an execution completes.
+type Labels struct {
+	Engine  string
+	Backend string
+	Outcome string
+}
+
+// Sink receives metrics emitted by gozero and the sandbox backends.
+// Implementations must be safe for concurrent use.
+type Sink interface {
+	// ExecutionStarted is called when an execution begins
+	ExecutionStarted(labels Labels)
+	// ExecutionFinished is called when an execution ends with the outcome set
+	ExecutionFinished(labels Labels, duration time.Duration, stdoutBytes, stderrBytes int)
+	// ContainerCreated is called when a sandbox backend creates a container
+	ContainerCreated(backend string)
+	// ContainerRemoved is called when a sandbox backend removes a container
+	ContainerRemoved(backend string)
+}
+
+// Discard is a sink which drops all metrics
+var Discard Sink = discard{}
+
+type discard struct{}
+
+func (discard) ExecutionStarted(Labels)                           {}
+func (discard) ExecutionFinished(Labels, time.Duration, int, int) {}
+func (discard) ContainerCreated(string)                           {}
+func (discard) ContainerRemoved(string)                           {}
+
+// OrDiscard returns sink or Discard when sink is nil
+func OrDiscard(sink Sink) Sink {
+	if sink == nil {
+		return Discard
+	}
+	return sink
+}
+
+// Observe reports the start of an execution to sink and returns a function
+// which must be called with its result to report completion.
+// ctx is the context the execution runs with and is used to classify timeouts
+func Observe(ctx context.Context, sink Sink, engine, backend string) func(res *types.Result, err error) {
+	sink = OrDiscard(sink)
+	labels := Labels{Engine: EngineLabel(engine), Backend: backend}
+	start := time.Now()
+	sink.ExecutionStarted(labels)
+	return func(res *types.Result, err error) {
+		var stdout, stderr int
+		if res != nil {
+			stdout, stderr = res.Stdout.Len(), res.Stderr.Len()
+		}
+		if err != nil && ctx.Err() != nil {
+			// the process is killed when the context is done, report why
+			err = errors.Join(ctx.Err(), err)
+		}
+		labels.Outcome = OutcomeOf(res, err)
+		sink.ExecutionFinished(labels, time.Since(start), stdout, stderr)
+	}
+}
+
+// EngineLabel normalizes an engine path to its base name (without .exe)
+// so that the engine label stays bounded across hosts
+func EngineLabel(engine string) string {
+	if engine == "" {
+		return ""
+	}
+	base := filepath.Base(engine)
+	if ext := filepath.Ext(base); strings.EqualFold(ext, ".exe") {
+		base = strings.TrimSuffix(base, ext)
+	}
+	return base
+}
+
+// OutcomeOf classifies the result of an execution into an outcome label value
+func OutcomeOf(res *types.Result, err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, types.ErrPolicyViolation):
+		return OutcomePolicyViolation
+	case errors.Is(err, types.ErrBackendUnavailable):
+		return OutcomeBackendUnavailable
+	case errors.Is(err, types.ErrStartFailed):
+		return OutcomeStartFailed
+	case errors.Is(err, types.ErrOOM):
+		return OutcomeOOM
+	case errors.Is(err, types.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return OutcomeTimeout
+	case errors.Is(err, context.Canceled):
+		return OutcomeCanceled
+	case errors.Is(err, types.ErrKilled):
+		return OutcomeKilled
+	case errors.Is(err, types.ErrNonZeroExit), res != nil && res.GetExitCode() != 0:
+		return OutcomeNonZeroExit
+	default:
+		return OutcomeError
+	}
+}
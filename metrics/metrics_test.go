@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"strings"
+	"testing"
+
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusSink(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	sink, err := NewPrometheus(reg, "")
+	require.Nil(t, err)
+
+	done := Observe(context.Background(), sink, "/usr/bin/python3", "local")
+	require.Equal(t, 1.0, testutil.ToFloat64(sink.inFlight.WithLabelValues("python3", "local")))
+
+	res := &types.Result{}
+	res.Stdout.WriteString("hello")
+	done(res, nil)
+	require.Equal(t, 0.0, testutil.ToFloat64(sink.inFlight.WithLabelValues("python3", "local")))
+	require.Equal(t, 1.0, testutil.ToFloat64(sink.executions.WithLabelValues("python3", "local", OutcomeSuccess)))
+	require.Equal(t, 5.0, testutil.ToFloat64(sink.stdoutBytes.WithLabelValues("python3", "local")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done = Observe(ctx, sink, "python3", "local")
+	cancel()
+	done(nil, errors.New("signal: killed"))
+	require.Equal(t, 1.0, testutil.ToFloat64(sink.executions.WithLabelValues("python3", "local", OutcomeCanceled)))
+
+	sink.ContainerCreated("docker")
+	sink.ContainerRemoved("docker")
+	require.Equal(t, 1.0, testutil.ToFloat64(sink.containersCreated.WithLabelValues("docker")))
+	require.Equal(t, 1.0, testutil.ToFloat64(sink.containersRemoved.WithLabelValues("docker")))
+
+	// registering twice on the same registry must fail
+	_, err = NewPrometheus(reg, "")
+	require.NotNil(t, err)
+}
+
+func TestExpvarSink(t *testing.T) {
+	sink := NewExpvar("gozero_test")
+	require.Same(t, sink.Map(), NewExpvar("gozero_test").Map())
+
+	done := Observe(context.Background(), sink, "/opt/python/python3.exe", "local")
+	res := &types.Result{}
+	res.Stderr.WriteString("boom")
+	done(res, errors.New("failed"))
+
+	require.Equal(t, "1", sink.executions.Get("python3/local/error").String())
+	require.Equal(t, "0", sink.inFlight.Get("python3/local").String())
+	require.Equal(t, "4", sink.stderrBytes.Get("python3/local").String())
+	require.True(t, strings.Contains(expvar.Get("gozero_test").String(), "executions_total"))
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusSink is a Sink which exports metrics as prometheus collectors
+type PrometheusSink struct {
+	executions        *prometheus.CounterVec
+	duration          *prometheus.HistogramVec
+	inFlight          *prometheus.GaugeVec
+	stdoutBytes       *prometheus.CounterVec
+	stderrBytes       *prometheus.CounterVec
+	containersCreated *prometheus.CounterVec
+	containersRemoved *prometheus.CounterVec
+}
+
+// NewPrometheus creates a prometheus sink and registers its collectors with reg.
+// When reg is nil prometheus.DefaultRegisterer is used
+func NewPrometheus(reg prometheus.Registerer, namespace string) (*PrometheusSink, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	if namespace == "" {
+		namespace = "gozero"
+	}
+	withOutcome := []string{"engine", "backend", "outcome"}
+	withoutOutcome := []string{"engine", "backend"}
+
+	p := &PrometheusSink{
+		executions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "executions_total",
+			Help:      "Total number of executions by outcome.",
+		}, withOutcome),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "execution_duration_seconds",
+			Help:      "Duration of executions in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+		}, withOutcome),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "executions_in_flight",
+			Help:      "Number of executions currently running.",
+		}, withoutOutcome),
+		stdoutBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "stdout_bytes_total",
+			Help:      "Total bytes written to stdout by executions.",
+		}, withoutOutcome),
+		stderrBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "stderr_bytes_total",
+			Help:      "Total bytes written to stderr by executions.",
+		}, withoutOutcome),
+		containersCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sandbox_containers_created_total",
+			Help:      "Total number of containers created by sandbox backends.",
+		}, []string{"backend"}),
+		containersRemoved: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sandbox_containers_removed_total",
+			Help:      "Total number of containers removed by sandbox backends.",
+		}, []string{"backend"}),
+	}
+	for _, c := range []prometheus.Collector{p.executions, p.duration, p.inFlight, p.stdoutBytes, p.stderrBytes, p.containersCreated, p.containersRemoved} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// ExecutionStarted implements Sink
+func (p *PrometheusSink) ExecutionStarted(labels Labels) {
+	p.inFlight.WithLabelValues(labels.Engine, labels.Backend).Inc()
+}
+
+// ExecutionFinished implements Sink
+func (p *PrometheusSink) ExecutionFinished(labels Labels, duration time.Duration, stdoutBytes, stderrBytes int) {
+	p.inFlight.WithLabelValues(labels.Engine, labels.Backend).Dec()
+	p.executions.WithLabelValues(labels.Engine, labels.Backend, labels.Outcome).Inc()
+	p.duration.WithLabelValues(labels.Engine, labels.Backend, labels.Outcome).Observe(duration.Seconds())
+	p.stdoutBytes.WithLabelValues(labels.Engine, labels.Backend).Add(float64(stdoutBytes))
+	p.stderrBytes.WithLabelValues(labels.Engine, labels.Backend).Add(float64(stderrBytes))
+}
+
+// ContainerCreated implements Sink
+func (p *PrometheusSink) ContainerCreated(backend string) {
+	p.containersCreated.WithLabelValues(backend).Inc()
+}
+
+// ContainerRemoved implements Sink
+func (p *PrometheusSink) ContainerRemoved(backend string) {
+	p.containersRemoved.WithLabelValues(backend).Inc()
+}
@@ -0,0 +1,80 @@
+package gozero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// MultiEngine evaluates every source with the engine of its language, detected
+// per source from its language hint, shebang, file extension or content (see
+// DetectLanguage). Engines are resolved on first use and cached
+type MultiEngine struct {
+	options *Options
+
+	mu      sync.Mutex
+	engines map[string]*Gozero
+}
+
+// NewMultiEngine creates a multi-engine executor. options are shared by all
+// engines, Engines (optional) are used for sources of unknown language
+func NewMultiEngine(options *Options) *MultiEngine {
+	return &MultiEngine{options: options, engines: map[string]*Gozero{}}
+}
+
+// Engine returns the executor of the engine detected for src
+func (m *MultiEngine) Engine(src *Source) (*Gozero, *Detection, error) {
+	detection, err := DetectLanguage(src)
+	if errors.Is(err, ErrUnknownLanguage) && src.Language == "" && len(m.options.Engines) > 0 {
+		detection, err = &Detection{Engines: m.options.Engines}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := strings.Join(detection.Engines, "\x00") + "\x01" + strings.Join(detection.Args, "\x00")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if g, ok := m.engines[key]; ok {
+		return g, detection, nil
+	}
+	options := *m.options
+	options.Engines = detection.Engines
+	options.Args = append(append([]string{}, detection.Args...), m.options.Args...)
+	g, err := New(&options)
+	if errors.Is(err, ErrNoValidEngine) {
+		language := detection.Language
+		if language == "" {
+			language = "unknown"
+		}
+		return nil, detection, fmt.Errorf("%w for %s source (tried %s)", err, language, strings.Join(detection.Engines, ", "))
+	}
+	if err != nil {
+		return nil, detection, err
+	}
+	m.engines[key] = g
+	return g, detection, nil
+}
+
+// Eval evaluates src with the engine detected for it
+// input = stdin , src = source code , args = arguments
+func (m *MultiEngine) Eval(ctx context.Context, src, input *Source, args ...string) (*types.Result, error) {
+	g, _, err := m.Engine(src)
+	if err != nil {
+		return nil, err
+	}
+	return g.Eval(ctx, src, input, args...)
+}
+
+// Start starts evaluating src with the engine detected for it (see Gozero.Start)
+func (m *MultiEngine) Start(ctx context.Context, src, input *Source, args ...string) (*Process, error) {
+	g, _, err := m.Engine(src)
+	if err != nil {
+		return nil, err
+	}
+	return g.Start(ctx, src, input, args...)
+}
@@ -1,13 +1,66 @@
 package gozero
 
+import (
+	"github.com/projectdiscovery/gozero/audit"
+	"github.com/projectdiscovery/gozero/cmdexec"
+	"github.com/projectdiscovery/gozero/metrics"
+	"github.com/projectdiscovery/gozero/policy"
+	"github.com/projectdiscovery/gozero/types"
+	"go.opentelemetry.io/otel/trace"
+)
+
 type Options struct {
-	Engines                  []string
-	Args                     []string
-	engine                   string
-	PreferStartProcess       bool
+	Engines []string
+	Args    []string
+	engine  string
+	// PreferStartProcess executes engines with the low level os.StartProcess
+	// api instead of os/exec. Engines found through a symlink always use it.
+	// It is not supported with Sandbox
+	PreferStartProcess bool
+	// Sandbox runs evaluations in the default sandbox of the platform
+	// (bubblewrap, then systemd-run on linux) without network access,
+	// with a read-only host filesystem and a private /tmp. Evaluations
+	// fail with types.ErrBackendUnavailable when no sandbox is available
 	Sandbox                  bool
 	EarlyCloseFileDescriptor bool
 	// When Debug Mode is set to true, Output result will contain
 	// more debug information
 	DebugMode bool
+	// TracerProvider is used to create spans for evaluations.
+	// When nil the global otel tracer provider is used
+	TracerProvider trace.TracerProvider
+	// Metrics receives execution metrics (nil disables metrics)
+	Metrics metrics.Sink
+	// Audit records every evaluation in a tamper-evident log (nil disables auditing)
+	Audit *audit.Logger
+	// Policy is checked before every evaluation, violations are returned
+	// as *policy.ViolationError (nil allows everything)
+	Policy *policy.Policy
+	// PTY runs evaluations attached to a pseudo-terminal (nil disables it).
+	// Expect steps configured here are run for every evaluation
+	PTY *cmdexec.PTYOptions
+	// SuccessCriteria declares the exit codes and stderr policy counting as success.
+	// It also applies to sandboxed evaluations unless their configuration sets its own
+	SuccessCriteria *types.SuccessCriteria
+	// Retry policy for transient failures (nil disables retries)
+	Retry *types.RetryPolicy
+	// Preflight checks the syntax of sources with the parser of the engine
+	// before running them, syntax errors are returned as *preflight.SyntaxError.
+	// Engines without a syntax check are run without it
+	Preflight bool
+	// CleanEnv runs evaluations without inheriting the environment of the
+	// current process, only source and input variables are passed
+	CleanEnv bool
+	// WorkDir is the working directory of evaluations (the current one when empty).
+	// With Sandbox it is only supported by the systemd backend
+	WorkDir string
+	// Credential runs evaluations as another user and group (unix only, requires
+	// root privileges). Sources are made readable by the user. It is not supported with Sandbox
+	Credential *cmdexec.Credential
+	// Transcript records the interleaved stdout and stderr of local and docker
+	// evaluations with their timestamps in Result.Transcript. It is not supported with Sandbox
+	Transcript bool
+	// NormalizeCharset detects the charset of stdout and stderr and transcodes
+	// them to UTF-8 (raw bytes remain available on the result)
+	NormalizeCharset bool
 }
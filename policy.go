@@ -0,0 +1,27 @@
+package gozero
+
+import (
+	"github.com/projectdiscovery/gozero/policy"
+)
+
+// enforcePolicy checks the evaluation of src by engine against the policy
+// when one is set. sandbox is nil for evaluations run without sandbox
+func (g *Gozero) enforcePolicy(engine string, engineArgs []string, src, input *Source, args []string, sandbox *policy.Sandbox) error {
+	if g.Options.Policy == nil {
+		return nil
+	}
+	req := &policy.Request{
+		Engine:     engine,
+		EngineArgs: engineArgs,
+		Args:       args,
+		Filename:   src.Filename,
+		Metadata:   src.Metadata,
+		Sandbox:    sandbox,
+	}
+	for _, source := range []*Source{src, input} {
+		if source != nil {
+			req.Variables = append(req.Variables, source.Variables...)
+		}
+	}
+	return g.Options.Policy.Enforce(req)
+}
@@ -0,0 +1,130 @@
+// policy package implements execution policies evaluated before every evaluation.
+//
+// A Policy is a list of rules over the engine, the source metadata, the
+// variables, the arguments and the sandbox of an evaluation. Violations are
+// returned as a *ViolationError matching types.ErrPolicyViolation, or only
+// reported when the policy runs in dry-run mode.
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// Request describes an evaluation checked against a policy
+type Request struct {
+	// Engine is the resolved path of the engine
+	Engine string
+	// EngineArgs are passed to the engine before the source
+	EngineArgs []string
+	// Args are passed to the source
+	Args []string
+	// Filename of the source
+	Filename string
+	// Metadata of the source (e.g. origin, trust)
+	Metadata map[string]string
+	// Variables of the source and input
+	Variables []types.Variable
+	// Sandbox of the evaluation (nil when run without sandbox)
+	Sandbox *Sandbox
+}
+
+// EngineName returns the base name of the engine without .exe suffix (e.g. python3)
+func (r *Request) EngineName() string {
+	return strings.TrimSuffix(filepath.Base(r.Engine), ".exe")
+}
+
+// Sandbox describes the sandbox of an evaluation
+type Sandbox struct {
+	// Backend is the name of the sandbox backend (e.g. docker)
+	Backend string
+	// NetworkDisabled is set when the sandbox has no network access
+	NetworkDisabled bool
+	// Config is the backend specific configuration (e.g. *sandbox.DockerConfiguration)
+	Config any
+}
+
+// Violation is a rule not satisfied by an evaluation
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+}
+
+// ViolationError is returned when an evaluation is rejected by policy.
+// It matches types.ErrPolicyViolation with errors.Is
+type ViolationError struct {
+	Violations []Violation
+}
+
+func (e *ViolationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.String())
+	}
+	return fmt.Sprintf("%s: %s", types.ErrPolicyViolation, strings.Join(messages, "; "))
+}
+
+// Is reports whether target is types.ErrPolicyViolation
+func (e *ViolationError) Is(target error) bool {
+	return target == types.ErrPolicyViolation
+}
+
+// Report is the outcome of checking a request against a policy
+type Report struct {
+	Request    *Request
+	Violations []Violation
+	// DryRun is set when violations did not block the evaluation
+	DryRun bool
+}
+
+// Allowed reports whether the request satisfies every rule
+func (r *Report) Allowed() bool {
+	return len(r.Violations) == 0
+}
+
+// Policy is a set of rules every evaluation must satisfy
+type Policy struct {
+	Rules []Rule
+	// DryRun reports violations without blocking evaluations
+	DryRun bool
+	// Reporter receives the report of every checked evaluation (optional)
+	Reporter func(*Report)
+}
+
+// New creates a policy enforcing rules
+func New(rules ...Rule) *Policy {
+	return &Policy{Rules: rules}
+}
+
+// Evaluate checks req against every rule and returns the report
+// without enforcing it
+func (p *Policy) Evaluate(req *Request) *Report {
+	report := &Report{Request: req, DryRun: p.DryRun}
+	for _, rule := range p.Rules {
+		report.Violations = append(report.Violations, rule.Check(req)...)
+	}
+	return report
+}
+
+// Enforce checks req and returns a *ViolationError when a rule is not
+// satisfied, unless the policy runs in dry-run mode. A nil policy allows everything
+func (p *Policy) Enforce(req *Request) error {
+	if p == nil {
+		return nil
+	}
+	report := p.Evaluate(req)
+	if p.Reporter != nil {
+		p.Reporter(report)
+	}
+	if report.Allowed() || p.DryRun {
+		return nil
+	}
+	return &ViolationError{Violations: report.Violations}
+}
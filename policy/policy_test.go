@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules(t *testing.T) {
+	untrusted := MetadataEquals("trust", "untrusted")
+	p := New(
+		AllowEngines("python3", "node"),
+		DenyVariables("AWS_*"),
+		DenyArgs("-c"),
+		When(untrusted, RequireSandbox(), RequireNetworkDisabled()),
+	)
+
+	report := p.Evaluate(&Request{
+		Engine:    "/usr/bin/python3",
+		Variables: []types.Variable{{Name: "NAME", Value: "x"}},
+		Metadata:  map[string]string{"trust": "trusted"},
+	})
+	require.True(t, report.Allowed(), report.Violations)
+
+	report = p.Evaluate(&Request{
+		Engine:     "/bin/bash",
+		EngineArgs: []string{"-c"},
+		Variables:  []types.Variable{{Name: "AWS_SECRET_ACCESS_KEY"}, {Name: "NAME"}},
+		Metadata:   map[string]string{"trust": "untrusted"},
+		Sandbox:    &Sandbox{Backend: "docker"},
+	})
+	var rules []string
+	for _, v := range report.Violations {
+		rules = append(rules, v.Rule)
+	}
+	require.Equal(t, []string{"allow-engines", "deny-variables", "deny-args", "require-network-disabled"}, rules)
+
+	report = p.Evaluate(&Request{
+		Engine:   "node",
+		Metadata: map[string]string{"trust": "untrusted"},
+		Sandbox:  &Sandbox{Backend: "docker", NetworkDisabled: true},
+	})
+	require.True(t, report.Allowed(), report.Violations)
+}
+
+func TestRequireSandbox(t *testing.T) {
+	rule := RequireSandbox("docker")
+	require.Len(t, rule.Check(&Request{}), 1)
+	require.Len(t, rule.Check(&Request{Sandbox: &Sandbox{Backend: "bwrap"}}), 1)
+	require.Empty(t, rule.Check(&Request{Sandbox: &Sandbox{Backend: "docker"}}))
+}
+
+func TestEnforce(t *testing.T) {
+	var reports []*Report
+	p := New(DenyEngines("bash"))
+	p.Reporter = func(r *Report) {
+		reports = append(reports, r)
+	}
+	req := &Request{Engine: "bash"}
+
+	err := p.Enforce(req)
+	require.ErrorIs(t, err, types.ErrPolicyViolation)
+	var violationErr *ViolationError
+	require.ErrorAs(t, err, &violationErr)
+	require.Equal(t, []Violation{{Rule: "deny-engines", Message: `engine "bash" is denied`}}, violationErr.Violations)
+	require.Equal(t, `execution policy violation: deny-engines: engine "bash" is denied`, err.Error())
+
+	p.DryRun = true
+	require.NoError(t, p.Enforce(req))
+	require.Len(t, reports, 2)
+	require.True(t, reports[1].DryRun)
+	require.False(t, reports[1].Allowed())
+
+	var nilPolicy *Policy
+	require.NoError(t, nilPolicy.Enforce(req))
+}
@@ -0,0 +1,150 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Rule checks a request and returns the violations found
+type Rule interface {
+	Check(req *Request) []Violation
+}
+
+// RuleFunc is a function used as a rule
+type RuleFunc func(req *Request) []Violation
+
+// Check calls f(req)
+func (f RuleFunc) Check(req *Request) []Violation {
+	return f(req)
+}
+
+// Condition selects the requests a rule applies to
+type Condition func(req *Request) bool
+
+// When applies rules only to the requests matching cond
+func When(cond Condition, rules ...Rule) Rule {
+	return RuleFunc(func(req *Request) []Violation {
+		if !cond(req) {
+			return nil
+		}
+		var violations []Violation
+		for _, rule := range rules {
+			violations = append(violations, rule.Check(req)...)
+		}
+		return violations
+	})
+}
+
+// MetadataEquals matches sources whose metadata key is set to value
+func MetadataEquals(key, value string) Condition {
+	return func(req *Request) bool {
+		v, ok := req.Metadata[key]
+		return ok && v == value
+	}
+}
+
+// MetadataNotEquals matches sources whose metadata key is not set to value
+// (including sources without the key)
+func MetadataNotEquals(key, value string) Condition {
+	return func(req *Request) bool {
+		v, ok := req.Metadata[key]
+		return !ok || v != value
+	}
+}
+
+// AllowEngines only allows the named engines (e.g. python3, node).
+// Names are matched against the base name of the engine
+func AllowEngines(names ...string) Rule {
+	return RuleFunc(func(req *Request) []Violation {
+		if slices.Contains(names, req.EngineName()) {
+			return nil
+		}
+		return []Violation{{
+			Rule:    "allow-engines",
+			Message: fmt.Sprintf("engine %q is not allowed (allowed: %s)", req.EngineName(), strings.Join(names, ", ")),
+		}}
+	})
+}
+
+// DenyEngines rejects the named engines
+func DenyEngines(names ...string) Rule {
+	return RuleFunc(func(req *Request) []Violation {
+		if !slices.Contains(names, req.EngineName()) {
+			return nil
+		}
+		return []Violation{{Rule: "deny-engines", Message: fmt.Sprintf("engine %q is denied", req.EngineName())}}
+	})
+}
+
+// DenyVariables rejects variables whose name matches one of the glob
+// patterns (e.g. AWS_*)
+func DenyVariables(patterns ...string) Rule {
+	return RuleFunc(func(req *Request) []Violation {
+		var violations []Violation
+		for _, v := range req.Variables {
+			if pattern, ok := match(patterns, v.Name); ok {
+				violations = append(violations, Violation{
+					Rule:    "deny-variables",
+					Message: fmt.Sprintf("variable %s matches denied pattern %q", v.Name, pattern),
+				})
+			}
+		}
+		return violations
+	})
+}
+
+// DenyArgs rejects engine or source arguments matching one of the glob
+// patterns (e.g. -c or --eval=*)
+func DenyArgs(patterns ...string) Rule {
+	return RuleFunc(func(req *Request) []Violation {
+		var violations []Violation
+		for _, arg := range slices.Concat(req.EngineArgs, req.Args) {
+			if pattern, ok := match(patterns, arg); ok {
+				violations = append(violations, Violation{
+					Rule:    "deny-args",
+					Message: fmt.Sprintf("argument %q matches denied pattern %q", arg, pattern),
+				})
+			}
+		}
+		return violations
+	})
+}
+
+// RequireSandbox requires evaluations to run in a sandbox,
+// restricted to backends when any are given
+func RequireSandbox(backends ...string) Rule {
+	return RuleFunc(func(req *Request) []Violation {
+		switch {
+		case req.Sandbox == nil:
+			return []Violation{{Rule: "require-sandbox", Message: "evaluation must run in a sandbox"}}
+		case len(backends) > 0 && !slices.Contains(backends, req.Sandbox.Backend):
+			return []Violation{{
+				Rule:    "require-sandbox",
+				Message: fmt.Sprintf("sandbox backend %q is not allowed (allowed: %s)", req.Sandbox.Backend, strings.Join(backends, ", ")),
+			}}
+		}
+		return nil
+	})
+}
+
+// RequireNetworkDisabled requires evaluations to run in a sandbox without network access
+func RequireNetworkDisabled() Rule {
+	return RuleFunc(func(req *Request) []Violation {
+		if req.Sandbox != nil && req.Sandbox.NetworkDisabled {
+			return nil
+		}
+		return []Violation{{Rule: "require-network-disabled", Message: "evaluation must run without network access"}}
+	})
+}
+
+// match returns the first pattern matching name
+func match(patterns []string, name string) (string, bool) {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,21 @@
+package gozero
+
+import (
+	"context"
+
+	"github.com/projectdiscovery/gozero/preflight"
+)
+
+// Preflight checks the syntax of src with the parser of the engine without
+// running it and returns the diagnostics found (empty when src parses)
+func (g *Gozero) Preflight(ctx context.Context, src *Source) ([]preflight.Diagnostic, error) {
+	return preflight.Check(ctx, g.Options.engine, src.Filename)
+}
+
+// preflight validates the syntax of src when enabled and supported by the engine
+func (g *Gozero) preflight(ctx context.Context, src *Source) error {
+	if !g.Options.Preflight || !preflight.Supported(g.Options.engine) {
+		return nil
+	}
+	return preflight.Validate(ctx, g.Options.engine, src.Filename)
+}
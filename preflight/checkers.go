@@ -0,0 +1,121 @@
+package preflight
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// checker runs the parser of a language and converts its output
+type checker struct {
+	args  func(filename string) []string
+	parse func(output string) []Diagnostic
+}
+
+// pythonCompile compiles the source without writing bytecode (unlike
+// py_compile) and prints the location of syntax errors
+const pythonCompile = `import sys
+try:
+    compile(open(sys.argv[1], 'rb').read(), sys.argv[1], 'exec', dont_inherit=True)
+except SyntaxError as e:
+    sys.stderr.write('%d:%d:%s\n' % (e.lineno or 0, e.offset or 0, e.msg))
+    sys.exit(1)
+`
+
+var (
+	pythonChecker = &checker{
+		args: func(filename string) []string { return []string{"-c", pythonCompile, filename} },
+		// 1:7:'(' was never closed
+		parse: lineParser(`^(\d+):(\d+):(.*)$`, func(m []string) Diagnostic {
+			return Diagnostic{Line: atoi(m[1]), Column: atoi(m[2]), Message: m[3]}
+		}),
+	}
+	nodeChecker = &checker{
+		args:  func(filename string) []string { return []string{"--check", filename} },
+		parse: parseNode,
+	}
+	shellChecker = &checker{
+		args: func(filename string) []string { return []string{"-n", filename} },
+		// bash: script.sh: line 3: syntax error near unexpected token `fi'
+		// dash: script.sh: 3: Syntax error: "fi" unexpected
+		// bash also echoes the offending line as script.sh: line 3: `fi fi'
+		parse: lineParser("^.*?: (?:line )?(\\d+): ([^`].*)$", func(m []string) Diagnostic {
+			return Diagnostic{Line: atoi(m[1]), Message: m[2]}
+		}),
+	}
+	rubyChecker = &checker{
+		args: func(filename string) []string { return []string{"-c", filename} },
+		// script.rb:3: syntax error, unexpected end-of-input
+		parse: lineParser(`^.*?:(\d+):(?:(\d+):)? (.*)$`, func(m []string) Diagnostic {
+			return Diagnostic{Line: atoi(m[1]), Column: atoi(m[2]), Message: m[3]}
+		}),
+	}
+	phpChecker = &checker{
+		args: func(filename string) []string { return []string{"-l", filename} },
+		// PHP Parse error:  syntax error, unexpected end of file in script.php on line 3
+		parse: lineParser(`error:\s+(.*) in .* on line (\d+)`, func(m []string) Diagnostic {
+			return Diagnostic{Line: atoi(m[2]), Message: m[1]}
+		}),
+	}
+)
+
+// lineParser returns a parser converting every output line matching
+// pattern to a diagnostic. Duplicated diagnostics are reported once
+func lineParser(pattern string, diagnostic func(match []string) Diagnostic) func(string) []Diagnostic {
+	re := regexp.MustCompile(pattern)
+	return func(output string) []Diagnostic {
+		var diagnostics []Diagnostic
+		seen := map[Diagnostic]struct{}{}
+		for _, line := range strings.Split(output, "\n") {
+			match := re.FindStringSubmatch(strings.TrimRight(line, "\r"))
+			if match == nil {
+				continue
+			}
+			d := diagnostic(match)
+			d.Message = strings.TrimSpace(d.Message)
+			if _, ok := seen[d]; ok {
+				// some engines report errors on both stdout and stderr
+				continue
+			}
+			seen[d] = struct{}{}
+			diagnostics = append(diagnostics, d)
+		}
+		return diagnostics
+	}
+}
+
+// parseNode parses the output of node --check:
+//
+//	/tmp/script.js:2
+//	  function (x {
+//	  ^^^^^^^^
+//
+//	SyntaxError: Function statements require a function name
+func parseNode(output string) []Diagnostic {
+	var d Diagnostic
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case d.Line == 0 && nodeLocationRe.MatchString(line):
+			d.Line = atoi(nodeLocationRe.FindStringSubmatch(line)[1])
+			// the source line is followed by the caret line
+			if i+2 < len(lines) {
+				if col := strings.Index(lines[i+2], "^"); col >= 0 {
+					d.Column = col + 1
+				}
+			}
+		case strings.HasPrefix(line, "SyntaxError: "):
+			d.Message = strings.TrimPrefix(line, "SyntaxError: ")
+			return []Diagnostic{d}
+		}
+	}
+	return nil
+}
+
+var nodeLocationRe = regexp.MustCompile(`:(\d+)$`)
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,128 @@
+// preflight package checks the syntax of sources by running only the parser
+// of their engine (e.g. node --check, bash -n), without executing them.
+//
+// Parser output is converted to structured diagnostics with the line and
+// column (when reported) of every error.
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrSyntax is returned when a source does not parse (see SyntaxError)
+	ErrSyntax = errors.New("source syntax error")
+	// ErrUnsupported is returned when an engine has no syntax check
+	ErrUnsupported = errors.New("preflight is not supported for engine")
+)
+
+// Diagnostic is a syntax error reported by the parser of an engine.
+// Line and Column are 1-based, zero when not reported
+type Diagnostic struct {
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	switch {
+	case d.Line > 0 && d.Column > 0:
+		return fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Message)
+	case d.Line > 0:
+		return fmt.Sprintf("%d: %s", d.Line, d.Message)
+	default:
+		return d.Message
+	}
+}
+
+// SyntaxError is returned when a source does not parse.
+// It matches ErrSyntax with errors.Is
+type SyntaxError struct {
+	Filename    string
+	Diagnostics []Diagnostic
+}
+
+func (e *SyntaxError) Error() string {
+	messages := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		messages = append(messages, fmt.Sprintf("%s:%s", e.Filename, d))
+	}
+	return fmt.Sprintf("%s: %s", ErrSyntax, strings.Join(messages, "; "))
+}
+
+// Is reports whether target is ErrSyntax
+func (e *SyntaxError) Is(target error) bool {
+	return target == ErrSyntax
+}
+
+// Supported reports whether engine (name or path) has a syntax check
+func Supported(engine string) bool {
+	_, ok := checkerOf(engine)
+	return ok
+}
+
+// Check runs the parser of engine on the source file and returns the
+// diagnostics found, which are empty when the source parses
+func Check(ctx context.Context, engine, filename string) ([]Diagnostic, error) {
+	c, ok := checkerOf(engine)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, engine)
+	}
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, engine, c.args(filename)...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	if err == nil {
+		return nil, nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || ctx.Err() != nil {
+		return nil, fmt.Errorf("could not run %s syntax check: %w", engine, err)
+	}
+	diagnostics := c.parse(output.String())
+	if len(diagnostics) == 0 {
+		// unknown output format, report it as is
+		diagnostics = []Diagnostic{{Message: strings.TrimSpace(output.String())}}
+	}
+	return diagnostics, nil
+}
+
+// Validate runs the parser of engine on the source file and returns
+// a *SyntaxError when it does not parse
+func Validate(ctx context.Context, engine, filename string) error {
+	diagnostics, err := Check(ctx, engine, filename)
+	if err != nil {
+		return err
+	}
+	if len(diagnostics) > 0 {
+		return &SyntaxError{Filename: filename, Diagnostics: diagnostics}
+	}
+	return nil
+}
+
+// checkerOf returns the checker of engine based on its base name
+// (e.g. /usr/bin/python3.11 is checked as python). Perl is not supported
+// since perl -c runs BEGIN and use blocks of the source on the host
+func checkerOf(engine string) (*checker, bool) {
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(engine)), ".exe")
+	switch {
+	case strings.HasPrefix(name, "python"), strings.HasPrefix(name, "pypy"):
+		return pythonChecker, true
+	case name == "node", name == "nodejs":
+		return nodeChecker, true
+	case name == "sh", name == "bash", name == "dash", name == "zsh", name == "ksh", name == "ash":
+		return shellChecker, true
+	case strings.HasPrefix(name, "ruby"):
+		return rubyChecker, true
+	case strings.HasPrefix(name, "php"):
+		return phpChecker, true
+	}
+	return nil, false
+}
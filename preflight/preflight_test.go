@@ -0,0 +1,93 @@
+package preflight
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		engine string
+		valid  string
+		broken string
+		want   []Diagnostic
+	}{
+		{
+			engine: "python3",
+			valid:  "print(1)\n",
+			broken: "x = 1\nif x:\n    print(\n",
+			want:   []Diagnostic{{Line: 3, Column: 10, Message: "'(' was never closed"}},
+		},
+		{
+			engine: "node",
+			valid:  "console.log(1)\n",
+			broken: "let a = 1;\n  function (x {\n",
+			want:   []Diagnostic{{Line: 2, Column: 3, Message: "Function statements require a function name"}},
+		},
+		{
+			engine: "bash",
+			valid:  "echo 1\n",
+			broken: "if true; then\n echo\nfi fi\n",
+			want:   []Diagnostic{{Line: 3, Message: "syntax error near unexpected token `fi'"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.engine, func(t *testing.T) {
+			engine, err := exec.LookPath(tt.engine)
+			if err != nil {
+				t.Skipf("%s not installed", tt.engine)
+			}
+			dir := t.TempDir()
+			valid := filepath.Join(dir, "valid")
+			require.NoError(t, os.WriteFile(valid, []byte(tt.valid), 0600))
+			broken := filepath.Join(dir, "broken")
+			require.NoError(t, os.WriteFile(broken, []byte(tt.broken), 0600))
+
+			diagnostics, err := Check(context.Background(), engine, valid)
+			require.NoError(t, err)
+			require.Empty(t, diagnostics)
+
+			err = Validate(context.Background(), engine, broken)
+			require.ErrorIs(t, err, ErrSyntax)
+			var syntaxErr *SyntaxError
+			require.ErrorAs(t, err, &syntaxErr)
+			require.Equal(t, tt.want, syntaxErr.Diagnostics)
+
+			// only the parser ran
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Len(t, entries, 2)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	require.Equal(t, []Diagnostic{
+		{Line: 3, Message: "syntax error, unexpected end-of-input"},
+		{Line: 4, Column: 2, Message: "unexpected token"},
+	}, rubyChecker.parse("script.rb:3: syntax error, unexpected end-of-input\nscript.rb:4:2: unexpected token\n"))
+
+	require.Equal(t, []Diagnostic{
+		{Line: 3, Message: "syntax error, unexpected end of file"},
+	}, phpChecker.parse("PHP Parse error:  syntax error, unexpected end of file in /tmp/script.php on line 3\n"+
+		"Parse error: syntax error, unexpected end of file in /tmp/script.php on line 3\nErrors parsing /tmp/script.php\n"))
+
+	require.Equal(t, []Diagnostic{
+		{Line: 2, Message: `Syntax error: newline unexpected (expecting ")")`},
+	}, shellChecker.parse("script.sh: 2: Syntax error: newline unexpected (expecting \")\")\n"))
+}
+
+func TestUnsupported(t *testing.T) {
+	require.True(t, Supported("/usr/bin/python3.11"))
+	require.True(t, Supported("node.exe"))
+	require.False(t, Supported("lua"))
+	// perl -c executes BEGIN blocks
+	require.False(t, Supported("/usr/bin/perl"))
+	_, err := Check(context.Background(), "lua", "script.lua")
+	require.ErrorIs(t, err, ErrUnsupported)
+}
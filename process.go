@@ -0,0 +1,91 @@
+package gozero
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gozero/cmdexec"
+	"github.com/projectdiscovery/gozero/internal/telemetry"
+	"github.com/projectdiscovery/gozero/metrics"
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/projectdiscovery/utils/errkit"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Process is a handle to an evaluation started with Gozero.Start.
+// It exposes the pid, stdin/stdout/stderr pipes and signals of the
+// underlying process. All methods are safe for concurrent use
+type Process struct {
+	*cmdexec.Process
+
+	g        *Gozero
+	finish   func(res *types.Result, err error, cleanup bool) error
+	waitOnce sync.Once
+	res      *types.Result
+	err      error
+}
+
+// Wait waits for the evaluation to exit, cleans up the sources and returns the result.
+// It can be called multiple times and from multiple goroutines
+func (p *Process) Wait() (*types.Result, error) {
+	p.waitOnce.Do(func() {
+		res, err := p.Process.Wait()
+		p.res, p.err = res, p.finish(res, p.g.normalizeOutput(res, err), true)
+	})
+	return p.res, p.err
+}
+
+// Start starts evaluating the source code without waiting for it to exit.
+// Stdin of the process is exposed by the returned handle, input (optional)
+// only provides variables. Once the process started, src and input are owned
+// by it and cleaned up by Process.Wait. They are left to the caller when
+// Start fails, like with Eval
+func (g *Gozero) Start(ctx context.Context, src, input *Source, args ...string) (*Process, error) {
+	ctx, span := telemetry.Tracer(g.Options.TracerProvider).Start(ctx, "gozero.Start",
+		trace.WithAttributes(
+			telemetry.AttrEngine.String(g.Options.engine),
+			telemetry.AttrBackend.String(telemetry.BackendLocal),
+		),
+	)
+	started := time.Now()
+	observe := metrics.Observe(ctx, g.Options.Metrics, g.Options.engine, telemetry.BackendLocal)
+	// cleanup is set once the process started and owns the sources
+	finish := func(res *types.Result, err error, cleanup bool) error {
+		if auditErr := g.auditEval(ctx, started, telemetry.BackendLocal, g.Options.Args, src, input, args, res, err); auditErr != nil {
+			err = errkit.Append(err, errkit.WithMessage(auditErr, "failed to write audit record"))
+		}
+		for _, source := range []*Source{src, input} {
+			if source == nil || !cleanup {
+				continue
+			}
+			if cleanupErr := source.Cleanup(); cleanupErr != nil {
+				err = errkit.Append(err, errkit.WithMessage(cleanupErr, "failed to cleanup source"))
+			}
+		}
+		observe(res, err)
+		telemetry.End(span, res, err)
+		return err
+	}
+
+	if g.Options.Sandbox {
+		// never run unsandboxed
+		return nil, finish(nil, fmt.Errorf("%w: Start", ErrSandboxUnsupported), false)
+	}
+	if err := g.enforcePolicy(g.Options.engine, g.Options.Args, src, input, args, nil); err != nil {
+		return nil, finish(nil, err, false)
+	}
+	if err := g.preflight(ctx, src); err != nil {
+		return nil, finish(nil, err, false)
+	}
+	gcmd, err := g.command(src, input, args)
+	if err != nil {
+		return nil, finish(nil, err, false)
+	}
+	proc, err := gcmd.Start(ctx)
+	if err != nil {
+		return nil, finish(nil, err, false)
+	}
+	return &Process{Process: proc, g: g, finish: finish}, nil
+}
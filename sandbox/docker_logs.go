@@ -0,0 +1,75 @@
+package sandbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// docker multiplexes the output of containers without tty in frames made of
+// an 8 bytes header (stream type and big endian payload size) and the payload
+const (
+	dockerFrameHeaderSize = 8
+	dockerStreamStdout    = 1
+	dockerStreamStderr    = 2
+)
+
+// demuxLogs reads the multiplexed log stream r into the stdout and stderr of res.
+// When timestamps were requested, every frame starts with its RFC3339 timestamp
+// which is removed from the output and recorded in the transcript of res (if any)
+// relative to started (the time of the first frame when zero)
+func demuxLogs(r io.Reader, res *types.Result, timestamps bool, started time.Time) error {
+	header := make([]byte, dockerFrameHeaderSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("invalid log frame header: %w", err)
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return fmt.Errorf("invalid log frame: %w", err)
+		}
+
+		var stream types.Stream
+		switch header[0] {
+		case dockerStreamStdout:
+			stream = types.StreamStdout
+		case dockerStreamStderr:
+			stream = types.StreamStderr
+		default:
+			// stdin is never logged, system errors are reported by the daemon
+			continue
+		}
+
+		var at time.Time
+		if timestamps {
+			if ts, data, ok := bytes.Cut(payload, []byte(" ")); ok {
+				if parsed, err := time.Parse(time.RFC3339Nano, string(ts)); err == nil {
+					at, payload = parsed, data
+				}
+			}
+		}
+		if stream == types.StreamStdout {
+			res.Stdout.Write(payload)
+		} else {
+			res.Stderr.Write(payload)
+		}
+		if res.Transcript != nil {
+			if started.IsZero() {
+				started = at
+			}
+			var elapsed time.Duration
+			if !at.IsZero() {
+				elapsed = max(at.Sub(started), 0)
+			}
+			res.Transcript.Append(types.Chunk{Stream: stream, Elapsed: elapsed, Data: payload})
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package sandbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/stretchr/testify/require"
+)
+
+// dockerFrame encodes payload as a frame of the multiplexed log stream
+func dockerFrame(stream byte, payload string) []byte {
+	frame := make([]byte, dockerFrameHeaderSize, dockerFrameHeaderSize+len(payload))
+	frame[0] = stream
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+func TestDemuxLogs(t *testing.T) {
+	var logs bytes.Buffer
+	logs.Write(dockerFrame(dockerStreamStdout, "2025-01-01T00:00:01.5Z out\n"))
+	logs.Write(dockerFrame(dockerStreamStderr, "2025-01-01T00:00:02Z err\n"))
+	logs.Write(dockerFrame(3, "daemon error"))
+	logs.Write(dockerFrame(dockerStreamStdout, "2025-01-01T00:00:03Z done\n"))
+
+	res := &types.Result{Transcript: types.NewTranscript()}
+	started := time.Date(2025, 1, 1, 0, 0, 1, 0, time.UTC)
+	require.Nil(t, demuxLogs(&logs, res, true, started))
+	require.Equal(t, "out\ndone\n", res.Stdout.String())
+	require.Equal(t, "err\n", res.Stderr.String())
+	require.Equal(t, ""+
+		"[    0.500s] stdout | out\n"+
+		"[    1.000s] stderr | err\n"+
+		"[    2.000s] stdout | done\n", res.Transcript.String())
+
+	// without timestamps nor transcript
+	logs.Reset()
+	logs.Write(dockerFrame(dockerStreamStdout, "2025 out\n"))
+	res = &types.Result{}
+	require.Nil(t, demuxLogs(&logs, res, false, time.Time{}))
+	require.Equal(t, "2025 out\n", res.Stdout.String())
+
+	// truncated frames are reported
+	require.Error(t, demuxLogs(bytes.NewReader(dockerFrame(dockerStreamStdout, "out")[:9]), &types.Result{}, false, time.Time{}))
+}
@@ -7,7 +7,10 @@ import (
 )
 
 type Sandbox interface {
+	// Run executes a command line, split into arguments with shell quoting rules (see shlex.Split)
 	Run(ctx context.Context, cmd string) (*types.Result, error)
+	// RunArgs executes the command args[0] with arguments args[1:]
+	RunArgs(ctx context.Context, args []string) (*types.Result, error)
 	RunScript(ctx context.Context, source string) (*types.Result, error)
 	RunSource(ctx context.Context, source string, interpreter string) (*types.Result, error)
 	Start() error
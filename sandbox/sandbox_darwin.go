@@ -11,14 +11,16 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
 
 	"github.com/projectdiscovery/gozero/cmdexec"
+	"github.com/projectdiscovery/gozero/shlex"
 	"github.com/projectdiscovery/gozero/types"
 )
 
 type Configuration struct {
 	Rules []Rule
+	// SuccessCriteria declares the exit codes and stderr policy counting as success
+	SuccessCriteria *types.SuccessCriteria
 }
 
 type Action string
@@ -66,7 +68,7 @@ type SandboxDarwin struct {
 // New sandbox with the given configuration
 func New(ctx context.Context, config *Configuration) (Sandbox, error) {
 	if ok, err := isSandboxExecInstalled(context.Background()); err != nil || !ok {
-		return nil, errors.New("sandbox feature not installed")
+		return nil, types.BackendUnavailableError(errors.New("sandbox feature not installed"))
 	}
 
 	sharedFolder, err := os.MkdirTemp("", "")
@@ -109,12 +111,25 @@ func New(ctx context.Context, config *Configuration) (Sandbox, error) {
 }
 
 func (s *SandboxDarwin) Run(ctx context.Context, cmd string) (*types.Result, error) {
+	args, err := shlex.Split(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return s.RunArgs(ctx, args)
+}
+
+// RunArgs executes the command args[0] with arguments args[1:] with sandbox-exec
+func (s *SandboxDarwin) RunArgs(ctx context.Context, args []string) (*types.Result, error) {
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
 	params := []string{"-f", s.confFile}
-	params = append(params, strings.Split(cmd, " ")...)
+	params = append(params, args...)
 	cmdContext, err := cmdexec.NewCommand("sandbox-exec", params...)
 	if err != nil {
 		return nil, err
 	}
+	cmdContext.SetSuccessCriteria(s.Config.SuccessCriteria)
 	return cmdContext.Execute(ctx)
 }
 
@@ -4,15 +4,22 @@ package sandbox
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/projectdiscovery/gozero/internal/telemetry"
+	"github.com/projectdiscovery/gozero/metrics"
+	"github.com/projectdiscovery/gozero/shlex"
 	"github.com/projectdiscovery/gozero/types"
 	"github.com/projectdiscovery/utils/errkit"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // BubblewrapConfiguration holds the general configuration for bubblewrap sandboxing
@@ -33,7 +40,7 @@ type BubblewrapConfiguration struct {
 	UID        int  // UID to run as inside the sandbox
 	GID        int  // GID to run as inside the sandbox
 
-	// Seccomp filter file
+	// Seccomp filter file (compiled BPF program, see bwrap --seccomp)
 	SeccompFile string
 
 	// Static bind mounts (read-only system directories)
@@ -42,8 +49,24 @@ type BubblewrapConfiguration struct {
 	// Static environment variables
 	Environment map[string]string
 
+	// Do not inherit the environment of the current process (requires bwrap 0.5.0),
+	// only Environment and the per-command variables are set
+	ClearEnvironment bool
+
 	// Enable host filesystem access (read-only)
 	HostFilesystem bool
+
+	// TracerProvider is used to trace sandbox operations (defaults to the global provider)
+	TracerProvider trace.TracerProvider
+
+	// Metrics receives execution metrics (nil disables metrics)
+	Metrics metrics.Sink
+
+	// SuccessCriteria declares the exit codes and stderr policy counting as success
+	SuccessCriteria *types.SuccessCriteria
+
+	// Retry policy for transient failures (nil disables retries)
+	Retry *types.RetryPolicy
 }
 
 // BubblewrapCommandOptions holds per-command configuration
@@ -51,6 +74,10 @@ type BubblewrapCommandOptions struct {
 	// The command to execute
 	Command string
 
+	// Engine labels the execution in metrics and traces, executions
+	// without one are labelled metrics.EngineCommand
+	Engine string
+
 	// Arguments for the command
 	Args []string
 
@@ -84,7 +111,9 @@ type Symlink struct {
 
 // BubblewrapSandbox implements sandboxing using bubblewrap (bwrap)
 type BubblewrapSandbox struct {
-	config *BubblewrapConfiguration
+	config  *BubblewrapConfiguration
+	tracer  trace.Tracer
+	metrics metrics.Sink
 }
 
 // NewBubblewrapSandbox creates a new bubblewrap sandbox
@@ -96,7 +125,7 @@ func NewBubblewrapSandbox(ctx context.Context, config *BubblewrapConfiguration)
 	// Check if bwrap is installed
 	installed, err := isBubblewrapInstalled(ctx)
 	if err != nil || !installed {
-		return nil, errors.New("bubblewrap (bwrap) is not installed")
+		return nil, types.BackendUnavailableError(errors.New("bubblewrap (bwrap) is not installed"))
 	}
 
 	// Set default temp directory if not provided
@@ -110,7 +139,9 @@ func NewBubblewrapSandbox(ctx context.Context, config *BubblewrapConfiguration)
 	}
 
 	return &BubblewrapSandbox{
-		config: config,
+		config:  config,
+		tracer:  telemetry.Tracer(config.TracerProvider),
+		metrics: metrics.OrDiscard(config.Metrics),
 	}, nil
 }
 
@@ -121,6 +152,12 @@ func validateBubblewrapConfig(config *BubblewrapConfiguration) error {
 		return fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
+	if config.SeccompFile != "" {
+		if _, err := os.Stat(config.SeccompFile); err != nil {
+			return fmt.Errorf("failed to read seccomp file: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -136,18 +173,23 @@ func isBubblewrapInstalled(ctx context.Context) (bool, error) {
 
 // Run executes a command in the bubblewrap sandbox with default options
 func (b *BubblewrapSandbox) Run(ctx context.Context, cmd string) (*types.Result, error) {
-	// Split command into executable and args
-	parts := strings.Fields(cmd)
-	if len(parts) == 0 {
-		return nil, errors.New("empty command")
+	// Split command into executable and args with shell quoting rules
+	args, err := shlex.Split(cmd)
+	if err != nil {
+		return nil, err
 	}
+	return b.RunArgs(ctx, args)
+}
 
-	executable := parts[0]
-	args := parts[1:]
+// RunArgs executes the command args[0] with arguments args[1:] in the bubblewrap sandbox with default options
+func (b *BubblewrapSandbox) RunArgs(ctx context.Context, args []string) (*types.Result, error) {
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
 
 	options := &BubblewrapCommandOptions{
-		Command: executable,
-		Args:    args,
+		Command: args[0],
+		Args:    args[1:],
 	}
 
 	return b.ExecuteWithOptions(ctx, options)
@@ -162,8 +204,10 @@ func (b *BubblewrapSandbox) RunScript(ctx context.Context, source string) (*type
 // It creates a specific source directory, places the script file inside, and mounts only that directory
 func (b *BubblewrapSandbox) RunSource(ctx context.Context, source string) (*types.Result, error) {
 	// Create a specific directory for this source execution
+	_, span := b.tracer.Start(ctx, "sandbox.bubblewrap.PrepareSource", bubblewrapSpanAttributes())
 	sourceDir, err := os.MkdirTemp(b.config.TempDir, "source_*")
 	if err != nil {
+		telemetry.EndErr(span, err)
 		return nil, fmt.Errorf("failed to create source directory: %w", err)
 	}
 	defer func() {
@@ -173,12 +217,15 @@ func (b *BubblewrapSandbox) RunSource(ctx context.Context, source string) (*type
 	// Create the script file in the source directory
 	scriptPath := filepath.Join(sourceDir, "script.sh")
 	if err := os.WriteFile(scriptPath, []byte(source), 0755); err != nil {
+		telemetry.EndErr(span, err)
 		return nil, fmt.Errorf("failed to write script to file: %w", err)
 	}
+	telemetry.EndErr(span, nil)
 
 	// Create options with bind mount for the source directory
 	options := &BubblewrapCommandOptions{
 		Command: "bash",
+		Engine:  "bash",
 		Args:    []string{"/src/script.sh"},
 		CommandBinds: []BindMount{
 			{
@@ -193,7 +240,7 @@ func (b *BubblewrapSandbox) RunSource(ctx context.Context, source string) (*type
 }
 
 // ExecuteWithOptions executes a command with specific per-command options
-func (b *BubblewrapSandbox) ExecuteWithOptions(ctx context.Context, options *BubblewrapCommandOptions) (*types.Result, error) {
+func (b *BubblewrapSandbox) ExecuteWithOptions(ctx context.Context, options *BubblewrapCommandOptions) (res *types.Result, err error) {
 	if options == nil {
 		return nil, errors.New("options cannot be nil")
 	}
@@ -202,8 +249,32 @@ func (b *BubblewrapSandbox) ExecuteWithOptions(ctx context.Context, options *Bub
 		return nil, errors.New("command cannot be empty")
 	}
 
+	// commands are free-form, the label is kept bounded
+	engine := options.Engine
+	if engine == "" {
+		engine = metrics.EngineCommand
+	}
+	ctx, span := b.tracer.Start(ctx, "sandbox.bubblewrap.Run", bubblewrapSpanAttributes(),
+		trace.WithAttributes(telemetry.AttrEngine.String(engine)),
+	)
+	observe := metrics.Observe(ctx, b.metrics, engine, telemetry.BackendBubblewrap)
+	defer func() {
+		observe(res, err)
+		telemetry.End(span, res, err)
+	}()
+
+	return types.Retry(ctx, b.config.Retry, func(ctx context.Context, _ int) (*types.Result, error) {
+		res, err := b.executeOnce(ctx, options)
+		return res, b.config.SuccessCriteria.Check(res, err)
+	})
+}
+
+// executeOnce runs the command once in a fresh sandbox root
+func (b *BubblewrapSandbox) executeOnce(ctx context.Context, options *BubblewrapCommandOptions) (*types.Result, error) {
 	// Create a unique sandbox directory for this command execution
+	_, phase := b.tracer.Start(ctx, "sandbox.bubblewrap.PrepareRoot", bubblewrapSpanAttributes())
 	sandboxDir, err := os.MkdirTemp(b.config.TempDir, "sandbox_*")
+	telemetry.EndErr(phase, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sandbox directory: %w", err)
 	}
@@ -215,19 +286,52 @@ func (b *BubblewrapSandbox) ExecuteWithOptions(ctx context.Context, options *Bub
 }
 
 // executeInSandbox executes a command in the bubblewrap sandbox
-func (b *BubblewrapSandbox) executeInSandbox(ctx context.Context, sandboxDir string, options *BubblewrapCommandOptions) (*types.Result, error) {
+func (b *BubblewrapSandbox) executeInSandbox(ctx context.Context, sandboxDir string, options *BubblewrapCommandOptions) (result *types.Result, err error) {
+	ctx, span := b.tracer.Start(ctx, "sandbox.bubblewrap.Execute", bubblewrapSpanAttributes())
+	defer func() {
+		telemetry.End(span, result, err)
+	}()
+
 	// Build the bwrap command with both static and per-command options
 	bwrapArgs := b.buildBubblewrapArgs(sandboxDir, options)
 
+	// Files passed to bwrap, the first one is fd 3
+	var extraFiles []*os.File
+	defer func() {
+		for _, f := range extraFiles {
+			_ = f.Close()
+		}
+	}()
+	if b.config.SeccompFile != "" {
+		seccomp, err := os.Open(b.config.SeccompFile)
+		if err != nil {
+			return nil, errkit.WithMessage(types.StartError(err), "failed to open seccomp file")
+		}
+		extraFiles = append(extraFiles, seccomp)
+		bwrapArgs = append(bwrapArgs, "--seccomp", strconv.Itoa(2+len(extraFiles)))
+	}
+
+	// bwrap reports the exit code of the command on its status fd (requires bwrap 0.5.0)
+	statusReader, statusWriter, err := os.Pipe()
+	if err != nil {
+		return nil, errkit.WithMessage(types.StartError(err), "failed to create bubblewrap status pipe")
+	}
+	defer func() {
+		_ = statusReader.Close()
+	}()
+	extraFiles = append(extraFiles, statusWriter)
+	bwrapArgs = append(bwrapArgs, "--json-status-fd", strconv.Itoa(2+len(extraFiles)))
+
 	// Add the command to execute
 	bwrapArgs = append(bwrapArgs, options.Command)
 	bwrapArgs = append(bwrapArgs, options.Args...)
 
 	// Execute the command
 	cmd := exec.CommandContext(ctx, "bwrap", bwrapArgs...)
+	cmd.ExtraFiles = extraFiles
 
 	// Create result
-	result := &types.Result{
+	result = &types.Result{
 		Command: fmt.Sprintf("bwrap %s", strings.Join(bwrapArgs, " ")),
 	}
 
@@ -242,19 +346,51 @@ func (b *BubblewrapSandbox) executeInSandbox(ctx context.Context, sandboxDir str
 
 	// Run the command
 	if err := cmd.Start(); err != nil {
-		return result, errkit.New("failed to start bubblewrap command: %w", err)
+		return result, errkit.WithMessage(types.StartError(err), "failed to start bubblewrap command")
 	}
+	// the status is read until bwrap (the only writer left) exits
+	_ = statusWriter.Close()
+	commandExited := make(chan bool, 1)
+	go func() {
+		commandExited <- readBubblewrapStatus(statusReader)
+	}()
 
 	if err := cmd.Wait(); err != nil {
-		if execErr, ok := err.(*exec.ExitError); ok {
+		execErr, ok := err.(*exec.ExitError)
+		if ok {
 			result.SetExitError(execErr)
 		}
-		return result, errkit.New("bubblewrap command failed: %w", err)
+		if ok && ctx.Err() == nil && !<-commandExited {
+			return result, errkit.WithMessagef(types.StartError(err), "bubblewrap setup failed got: %v", result.Stderr.String())
+		}
+		return result, errkit.WithMessagef(types.WaitError(ctx, err), "bubblewrap command failed got: %v", result.Stderr.String())
 	}
 
 	return result, nil
 }
 
+// readBubblewrapStatus reads the json status of bwrap until it is closed and
+// reports whether bwrap reported the exit code of the command. bwrap fails
+// without reporting it when the sandbox cannot be set up, unlike the output
+// of the command this cannot be faked by the command
+func readBubblewrapStatus(r io.Reader) bool {
+	exited := false
+	decoder := json.NewDecoder(r)
+	for {
+		var status struct {
+			ExitCode *int `json:"exit-code"`
+		}
+		if err := decoder.Decode(&status); err != nil {
+			// drain the pipe so that bwrap never blocks on it
+			_, _ = io.Copy(io.Discard, r)
+			return exited
+		}
+		if status.ExitCode != nil {
+			exited = true
+		}
+	}
+}
+
 // buildBubblewrapArgs constructs the bwrap command arguments
 func (b *BubblewrapSandbox) buildBubblewrapArgs(sandboxDir string, options *BubblewrapCommandOptions) []string {
 	args := []string{}
@@ -325,6 +461,11 @@ func (b *BubblewrapSandbox) buildBubblewrapArgs(sandboxDir string, options *Bubb
 		args = append(args, "--ro-bind", hostPath, bind.SandboxPath)
 	}
 
+	// Clear the inherited environment before setting variables
+	if b.config.ClearEnvironment {
+		args = append(args, "--clearenv")
+	}
+
 	// Add static environment variables
 	for key, value := range b.config.Environment {
 		args = append(args, "--setenv", key, value)
@@ -352,6 +493,11 @@ func (b *BubblewrapSandbox) buildBubblewrapArgs(sandboxDir string, options *Bubb
 	return args
 }
 
+// bubblewrapSpanAttributes returns the span start options common to bubblewrap sandbox spans
+func bubblewrapSpanAttributes() trace.SpanStartOption {
+	return trace.WithAttributes(telemetry.AttrBackend.String(telemetry.BackendBubblewrap))
+}
+
 // Start starts the sandbox (no-op for bubblewrap as it runs per-command)
 func (b *BubblewrapSandbox) Start() error {
 	return nil
@@ -0,0 +1,94 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/gozero/metrics"
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/stretchr/testify/require"
+)
+
+// labelSink records the labels of finished executions
+type labelSink struct {
+	metrics.Sink
+	finished []metrics.Labels
+}
+
+func (s *labelSink) ExecutionFinished(labels metrics.Labels, _ time.Duration, _, _ int) {
+	s.finished = append(s.finished, labels)
+}
+
+// fakeBubblewrap puts a bwrap running script on PATH
+func fakeBubblewrap(t *testing.T, script string) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "bwrap"), []byte("#!/bin/sh\n"+script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestBubblewrapEngineLabel(t *testing.T) {
+	fakeBubblewrap(t, "exit 0\n")
+	sink := &labelSink{Sink: metrics.Discard}
+	bwrap, err := NewBubblewrapSandbox(context.Background(), &BubblewrapConfiguration{Metrics: sink})
+	require.Nil(t, err)
+
+	// free-form commands do not end up in the engine label
+	_, err = bwrap.ExecuteWithOptions(context.Background(), &BubblewrapCommandOptions{Command: "/tmp/x/" + t.Name()})
+	require.Nil(t, err)
+	_, err = bwrap.ExecuteWithOptions(context.Background(), &BubblewrapCommandOptions{Command: "/usr/bin/python3", Engine: "/usr/bin/python3"})
+	require.Nil(t, err)
+	require.Len(t, sink.finished, 2)
+	require.Equal(t, metrics.EngineCommand, sink.finished[0].Engine)
+	require.Equal(t, "python3", sink.finished[1].Engine)
+}
+
+func TestBubblewrapSetupFailure(t *testing.T) {
+	// the fake bwrap reports the exit code of the command when FAKE_BWRAP_STARTED is set
+	fakeBubblewrap(t, `[ "$1" = --help ] && exit 0
+while [ $# -gt 0 ]; do [ "$1" = --json-status-fd ] && fd=$2; shift; done
+printf "$FAKE_BWRAP_STDERR" >&2
+[ -n "$FAKE_BWRAP_STARTED" ] && eval "echo '{\"child-pid\": 2}' >&$fd; echo '{\"exit-code\": 1}' >&$fd"
+exit 1
+`)
+	bwrap, err := NewBubblewrapSandbox(context.Background(), &BubblewrapConfiguration{})
+	require.Nil(t, err)
+
+	t.Setenv("FAKE_BWRAP_STDERR", `bwrap: Can't mount proc on /newroot/proc: Operation not permitted\n`)
+	_, err = bwrap.ExecuteWithOptions(context.Background(), &BubblewrapCommandOptions{Command: "true"})
+	require.ErrorIs(t, err, types.ErrStartFailed)
+
+	// the same output from the command itself is not a setup failure
+	t.Setenv("FAKE_BWRAP_STARTED", "1")
+	_, err = bwrap.ExecuteWithOptions(context.Background(), &BubblewrapCommandOptions{Command: "true"})
+	require.ErrorIs(t, err, types.ErrNonZeroExit)
+	require.NotErrorIs(t, err, types.ErrStartFailed)
+}
+
+func TestBubblewrapSeccomp(t *testing.T) {
+	dir := t.TempDir()
+	fakeBubblewrap(t, `[ "$1" = --help ] && exit 0; echo "$@" > "$FAKE_BWRAP_DIR/args"; cat <&3 > "$FAKE_BWRAP_DIR/seccomp"`+"\n")
+	t.Setenv("FAKE_BWRAP_DIR", dir)
+	seccompFile := filepath.Join(dir, "filter.bpf")
+	require.Nil(t, os.WriteFile(seccompFile, []byte("bpf"), 0644))
+
+	_, err := NewBubblewrapSandbox(context.Background(), &BubblewrapConfiguration{SeccompFile: filepath.Join(dir, "missing")})
+	require.NotNil(t, err)
+
+	bwrap, err := NewBubblewrapSandbox(context.Background(), &BubblewrapConfiguration{SeccompFile: seccompFile})
+	require.Nil(t, err)
+	_, err = bwrap.ExecuteWithOptions(context.Background(), &BubblewrapCommandOptions{Command: "true"})
+	require.Nil(t, err)
+
+	// the filter is passed to bwrap as fd 3
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	require.Nil(t, err)
+	require.Contains(t, string(args), "--seccomp 3 ")
+	filter, err := os.ReadFile(filepath.Join(dir, "seccomp"))
+	require.Nil(t, err)
+	require.Equal(t, "bpf", string(filter))
+}
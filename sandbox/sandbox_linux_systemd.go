@@ -5,16 +5,23 @@ package sandbox
 import (
 	"context"
 	"errors"
-	"os/exec"
+	"sort"
 	"strings"
+	"syscall"
 
 	"github.com/projectdiscovery/gozero/cmdexec"
+	"github.com/projectdiscovery/gozero/shlex"
 	"github.com/projectdiscovery/gozero/types"
+	"github.com/projectdiscovery/utils/errkit"
 	stringsutil "github.com/projectdiscovery/utils/strings"
 )
 
 type Configuration struct {
 	Rules []Rule
+	// Environment variables set in the transient unit
+	Environment map[string]string
+	// SuccessCriteria declares the exit codes and stderr policy counting as success
+	SuccessCriteria *types.SuccessCriteria
 }
 
 type Filter string
@@ -40,6 +47,8 @@ const (
 	PrivateMounts           Filter = "PrivateMounts"
 	DynamicUser             Filter = "DynamicUser"
 	SystemCallFilter        Filter = "SystemCallFilter"
+	MemoryMax               Filter = "MemoryMax"
+	CPUQuota                Filter = "CPUQuota"
 )
 
 type ArgsType uint8
@@ -50,6 +59,8 @@ const (
 	Capabilities
 	Namespaces
 	SystemCalls
+	// Value is a free-form property value (e.g. MemoryMax=512M)
+	Value
 )
 
 type Arg struct {
@@ -62,6 +73,25 @@ type Rule struct {
 	Arg    Arg
 }
 
+// systemdOOMResult is the result of units killed by the OOM killer
+const systemdOOMResult = "oom-kill"
+
+// isSystemdOOM reports whether the status lines systemd-run writes once the
+// unit exited ("Finished with result: ..." and "Main processes terminated
+// with: ...") report an OOM kill. Other output of the command is ignored
+func isSystemdOOM(stderr string) bool {
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "Finished with result: "+systemdOOMResult {
+			return true
+		}
+		if status, ok := strings.CutPrefix(line, "Main processes terminated with: "); ok && strings.Contains(status, systemdOOMResult) {
+			return true
+		}
+	}
+	return false
+}
+
 // Sandbox native on linux
 type SandboxLinux struct {
 	Config *Configuration
@@ -69,18 +99,20 @@ type SandboxLinux struct {
 }
 
 func isSystemdInstalled(ctx context.Context) (bool, error) {
-	cmd := exec.CommandContext(ctx, "systemd-run", "--help")
-	err := cmd.Run()
+	cmd, err := cmdexec.NewCommand("systemd-run", "--help")
 	if err != nil {
 		return false, err
 	}
+	if _, err := cmd.Execute(ctx); err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
 // New sandbox with the given configuration
 func New(ctx context.Context, config *Configuration) (Sandbox, error) {
 	if ok, err := isSystemdInstalled(context.Background()); err != nil || !ok {
-		return nil, errors.New("sandbox feature not installed")
+		return nil, types.BackendUnavailableError(errors.New("sandbox feature not installed"))
 	}
 
 	conf := []string{"--pipe", "--pty", "--user"}
@@ -100,6 +132,12 @@ func New(ctx context.Context, config *Configuration) (Sandbox, error) {
 				return nil, errors.New("invalid value (yes/no)")
 			}
 			actionArgs = append(actionArgs, string(rule.Filter)+"="+v)
+		case Value:
+			v, ok := rule.Arg.Params.(string)
+			if !ok || v == "" {
+				return nil, errors.New("invalid string value")
+			}
+			actionArgs = append(actionArgs, string(rule.Filter)+"="+v)
 		case Folders, Capabilities, Namespaces, SystemCalls:
 			v, ok := rule.Arg.Params.([]string)
 			if !ok {
@@ -112,19 +150,77 @@ func New(ctx context.Context, config *Configuration) (Sandbox, error) {
 		conf = append(conf, actionArgs...)
 	}
 
+	envNames := make([]string, 0, len(config.Environment))
+	for name := range config.Environment {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+	for _, name := range envNames {
+		conf = append(conf, "--setenv="+name+"="+config.Environment[name])
+	}
+
 	s := &SandboxLinux{Config: config, conf: conf}
 	return s, nil
 }
 
+// SystemdCommandOptions holds the per-command configuration of a systemd execution
+type SystemdCommandOptions struct {
+	// The command to execute
+	Command string
+
+	// Arguments for the command
+	Args []string
+
+	// Input for stdin
+	Stdin string
+
+	// Working directory of the command in the unit (the default of the unit when empty)
+	WorkingDir string
+}
+
 func (s *SandboxLinux) Run(ctx context.Context, cmd string) (*types.Result, error) {
+	args, err := shlex.Split(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return s.RunArgs(ctx, args)
+}
+
+// RunArgs executes the command args[0] with arguments args[1:] in a transient unit
+func (s *SandboxLinux) RunArgs(ctx context.Context, args []string) (*types.Result, error) {
+	if len(args) == 0 {
+		return nil, errors.New("command cannot be empty")
+	}
+	return s.ExecuteWithOptions(ctx, &SystemdCommandOptions{Command: args[0], Args: args[1:]})
+}
+
+// ExecuteWithOptions executes a command in a transient unit with the given options
+func (s *SandboxLinux) ExecuteWithOptions(ctx context.Context, options *SystemdCommandOptions) (*types.Result, error) {
+	if options == nil || options.Command == "" {
+		return nil, errors.New("command cannot be empty")
+	}
 	var params []string
 	params = append(params, s.conf...)
-	params = append(params, strings.Split(cmd, " ")...)
+	if options.WorkingDir != "" {
+		params = append(params, "--working-directory="+options.WorkingDir)
+	}
+	params = append(params, options.Command)
+	params = append(params, options.Args...)
 	cmdContext, err := cmdexec.NewCommand("systemd-run", params...)
 	if err != nil {
 		return nil, err
 	}
-	return cmdContext.Execute(ctx)
+	cmdContext.SetSuccessCriteria(s.Config.SuccessCriteria)
+	// systemd-run must not outlive the current process
+	cmdContext.SetPdeathsig(syscall.SIGKILL)
+	if options.Stdin != "" {
+		cmdContext.SetStdin(strings.NewReader(options.Stdin))
+	}
+	res, err := cmdContext.Execute(ctx)
+	if err != nil && res != nil && isSystemdOOM(res.Stderr.String()) {
+		err = errkit.Append(types.ErrOOM, err)
+	}
+	return res, err
 }
 
 // RunScript executes a script or source code in the sandbox
@@ -0,0 +1,33 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectdiscovery/gozero/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemdOOM(t *testing.T) {
+	// the fake systemd-run writes FAKE_SYSTEMD_STDERR and exits 1
+	dir := t.TempDir()
+	fake := "#!/bin/sh\n[ \"$1\" = --help ] && exit 0\nprintf \"$FAKE_SYSTEMD_STDERR\" >&2\nexit 1\n"
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "systemd-run"), []byte(fake), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	systemd, err := New(context.Background(), &Configuration{})
+	require.Nil(t, err)
+
+	t.Setenv("FAKE_SYSTEMD_STDERR", `Finished with result: oom-kill\nMain processes terminated with: code=killed/status=KILL\n`)
+	_, err = systemd.RunArgs(context.Background(), []string{"true"})
+	require.ErrorIs(t, err, types.ErrOOM)
+
+	// output of the command itself is not a status line
+	t.Setenv("FAKE_SYSTEMD_STDERR", `oom-kill\n`)
+	_, err = systemd.RunArgs(context.Background(), []string{"true"})
+	require.ErrorIs(t, err, types.ErrNonZeroExit)
+	require.NotErrorIs(t, err, types.ErrOOM)
+}
@@ -66,7 +66,7 @@ type SandboxWindows struct {
 // New sandbox with the given configuration
 func New(ctx context.Context, config *Configuration) (Sandbox, error) {
 	if ok, err := isInstalled(ctx); err != nil || !ok {
-		return nil, err
+		return nil, types.BackendUnavailableError(err)
 	}
 
 	sharedFolder, err := os.MkdirTemp("", "")
@@ -119,6 +119,11 @@ func (s *SandboxWindows) Run(ctx context.Context, cmd string) (*types.Result, er
 	return nil, ErrAgentRequired
 }
 
+// RunArgs executes a command in the sandbox
+func (s *SandboxWindows) RunArgs(ctx context.Context, args []string) (*types.Result, error) {
+	return nil, ErrAgentRequired
+}
+
 // RunScript executes a script or source code in the sandbox
 func (s *SandboxWindows) RunScript(ctx context.Context, source string) (*types.Result, error) {
 	return nil, ErrNotImplemented
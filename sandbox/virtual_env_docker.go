@@ -2,16 +2,23 @@ package sandbox
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
+	"github.com/projectdiscovery/gozero/internal/telemetry"
+	"github.com/projectdiscovery/gozero/metrics"
+	"github.com/projectdiscovery/gozero/shlex"
 	"github.com/projectdiscovery/gozero/types"
+	"github.com/projectdiscovery/utils/errkit"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // DockerConfiguration represents the configuration for Docker sandbox
@@ -25,20 +32,39 @@ type DockerConfiguration struct {
 	Memory          string            // Memory limit (e.g., "512m", "1g")
 	CPULimit        string            // CPU limit (e.g., "0.5", "1.0")
 	Timeout         time.Duration     // Command timeout
-	Remove          bool              // Whether to remove container after execution
+	Remove          bool              // Whether to remove container after execution (kept containers are listed with docker ps -a)
+	// TracerProvider is used to trace sandbox operations (defaults to the global provider)
+	TracerProvider trace.TracerProvider `json:"-"`
+	// Metrics receives execution and container metrics (nil disables metrics)
+	Metrics metrics.Sink `json:"-"`
+	// SuccessCriteria declares the exit codes and stderr policy counting as success
+	SuccessCriteria *types.SuccessCriteria
+	// Retry policy for transient failures (nil disables retries)
+	Retry *types.RetryPolicy
+	// Transcript records the interleaved output of the container with the
+	// timestamps of its log stream, relative to the container start
+	Transcript bool
 }
 
 // SandboxDocker implements the Sandbox interface using Docker containers
 type SandboxDocker struct {
 	config       *DockerConfiguration
 	dockerClient *client.Client
+	tracer       trace.Tracer
+	metrics      metrics.Sink
 }
 
 // NewDockerSandbox creates a new Docker-based sandbox
-func NewDockerSandbox(ctx context.Context, config *DockerConfiguration) (*SandboxDocker, error) {
+func NewDockerSandbox(ctx context.Context, config *DockerConfiguration) (_ *SandboxDocker, err error) {
+	tracer := telemetry.Tracer(config.TracerProvider)
+	ctx, span := tracer.Start(ctx, "sandbox.docker.New", dockerSpanAttributes())
+	defer func() {
+		telemetry.EndErr(span, err)
+	}()
+
 	// Check if Docker is available
 	if ok, err := isDockerInstalled(ctx); err != nil || !ok {
-		return nil, fmt.Errorf("docker not available: %w", err)
+		return nil, types.BackendUnavailableError(fmt.Errorf("docker not available: %w", err))
 	}
 
 	// Create Docker client
@@ -47,13 +73,13 @@ func NewDockerSandbox(ctx context.Context, config *DockerConfiguration) (*Sandbo
 		client.FromEnv,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create docker client: %w", err)
+		return nil, types.BackendUnavailableError(fmt.Errorf("failed to create docker client: %w", err))
 	}
 
 	// Test Docker connection
 	_, err = dockerClient.Ping(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to docker daemon: %w", err)
+		return nil, types.BackendUnavailableError(fmt.Errorf("failed to connect to docker daemon: %w", err))
 	}
 
 	// Validate required configuration
@@ -63,25 +89,44 @@ func NewDockerSandbox(ctx context.Context, config *DockerConfiguration) (*Sandbo
 	if config.WorkingDir == "" {
 		return nil, fmt.Errorf("working directory must be specified")
 	}
+	if _, err := parseCPULimit(config.CPULimit); err != nil {
+		return nil, err
+	}
 	if config.Timeout == 0 {
 		config.Timeout = 30 * time.Second
 	}
-	if config.Remove {
-		config.Remove = true // Default to removing containers
-	}
 
 	return &SandboxDocker{
 		config:       config,
 		dockerClient: dockerClient,
+		tracer:       tracer,
+		metrics:      metrics.OrDiscard(config.Metrics),
 	}, nil
 }
 
 // runCommand executes a command in the Docker container with the given command parts
-func (s *SandboxDocker) runCommand(ctx context.Context, cmdParts []string, command string, createFile bool, fileContent string) (*types.Result, error) {
+func (s *SandboxDocker) runCommand(ctx context.Context, engine string, cmdParts []string, command string, createFile bool, fileContent string) (res *types.Result, err error) {
+	ctx, span := s.tracer.Start(ctx, "sandbox.docker.Run", dockerSpanAttributes(),
+		trace.WithAttributes(telemetry.AttrEngine.String(engine)),
+	)
+	observe := metrics.Observe(ctx, s.metrics, engine, telemetry.BackendDocker)
+	defer func() {
+		observe(res, err)
+		telemetry.End(span, res, err)
+	}()
+
 	if len(cmdParts) == 0 {
 		return nil, fmt.Errorf("empty command")
 	}
 
+	return types.Retry(ctx, s.config.Retry, func(ctx context.Context, _ int) (*types.Result, error) {
+		res, err := s.runContainer(ctx, cmdParts, command, createFile, fileContent)
+		return res, s.config.SuccessCriteria.Check(res, err)
+	})
+}
+
+// runContainer runs the command once in a new container
+func (s *SandboxDocker) runContainer(ctx context.Context, cmdParts []string, command string, createFile bool, fileContent string) (*types.Result, error) {
 	// Create a new context with timeout
 	runCtx, cancel := context.WithTimeout(ctx, s.config.Timeout)
 	defer cancel()
@@ -123,95 +168,126 @@ exec %s
 	}
 
 	// Create host configuration
-	hostConfig := &container.HostConfig{
-		AutoRemove: false, // Don't auto-remove so we can get logs
-	}
-
-	// Set network configuration
-	if s.config.NetworkDisabled {
-		hostConfig.NetworkMode = "none"
-	} else if s.config.NetworkMode != "" {
-		hostConfig.NetworkMode = container.NetworkMode(s.config.NetworkMode)
-	}
-
-	// Set resource limits if specified
-	if s.config.Memory != "" {
-		hostConfig.Memory = parseMemoryLimit(s.config.Memory)
+	hostConfig, err := dockerHostConfig(s.config)
+	if err != nil {
+		return nil, err
 	}
 
 	// Pull image if it doesn't exist locally
-	err := s.pullImageIfNeeded(runCtx, s.config.Image)
+	err = s.pullImageIfNeeded(runCtx, s.config.Image)
 	if err != nil {
-		return nil, fmt.Errorf("failed to pull image %s: %w", s.config.Image, err)
+		return nil, types.StartError(fmt.Errorf("failed to pull image %s: %w", s.config.Image, err))
 	}
 
 	// Create container
-	createResp, err := s.dockerClient.ContainerCreate(runCtx, containerConfig, hostConfig, nil, nil, "")
+	phaseCtx, phase := s.tracer.Start(runCtx, "sandbox.docker.ContainerCreate", dockerSpanAttributes())
+	createResp, err := s.dockerClient.ContainerCreate(phaseCtx, containerConfig, hostConfig, nil, nil, "")
+	telemetry.EndErr(phase, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create container: %w", err)
+		return nil, types.StartError(fmt.Errorf("failed to create container: %w", err))
 	}
 
 	containerID := createResp.ID
+	s.metrics.ContainerCreated(telemetry.BackendDocker)
 
 	// Start container
-	err = s.dockerClient.ContainerStart(runCtx, containerID, container.StartOptions{})
+	phaseCtx, phase = s.tracer.Start(runCtx, "sandbox.docker.ContainerStart", dockerSpanAttributes())
+	err = s.dockerClient.ContainerStart(phaseCtx, containerID, container.StartOptions{})
+	telemetry.EndErr(phase, err)
 	if err != nil {
-		_ = s.dockerClient.ContainerRemove(runCtx, containerID, container.RemoveOptions{Force: true})
-		return nil, fmt.Errorf("failed to start container: %w", err)
+		s.removeContainer(runCtx, containerID)
+		return nil, types.StartError(fmt.Errorf("failed to start container: %w", err))
 	}
 
 	// Wait for container to finish
-	waitCh, errCh := s.dockerClient.ContainerWait(runCtx, containerID, container.WaitConditionNotRunning)
+	phaseCtx, phase = s.tracer.Start(runCtx, "sandbox.docker.ContainerWait", dockerSpanAttributes())
+	waitCh, errCh := s.dockerClient.ContainerWait(phaseCtx, containerID, container.WaitConditionNotRunning)
 
 	select {
 	case err := <-errCh:
-		_ = s.dockerClient.ContainerRemove(runCtx, containerID, container.RemoveOptions{Force: true})
+		telemetry.EndErr(phase, err)
+		if !s.config.Remove {
+			// kept containers do not outlive the execution
+			_ = s.dockerClient.ContainerKill(context.WithoutCancel(runCtx), containerID, "KILL")
+		}
+		s.removeContainer(context.WithoutCancel(runCtx), containerID)
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return nil, errkit.Append(types.ErrTimeout, fmt.Errorf("container wait error: %w", err))
+		}
 		return nil, fmt.Errorf("container wait error: %w", err)
 	case result := <-waitCh:
+		telemetry.EndErr(phase, nil)
+
 		// Get container logs
-		logs, err := s.dockerClient.ContainerLogs(runCtx, containerID, container.LogsOptions{
+		phaseCtx, phase = s.tracer.Start(runCtx, "sandbox.docker.ContainerLogs", dockerSpanAttributes())
+		logs, err := s.dockerClient.ContainerLogs(phaseCtx, containerID, container.LogsOptions{
 			ShowStdout: true,
 			ShowStderr: true,
+			Timestamps: s.config.Transcript,
 		})
 		if err != nil {
-			_ = s.dockerClient.ContainerRemove(runCtx, containerID, container.RemoveOptions{Force: true})
+			telemetry.EndErr(phase, err)
+			s.removeContainer(runCtx, containerID)
 			return nil, fmt.Errorf("failed to get container logs: %w", err)
 		}
 		defer func() {
 			_ = logs.Close()
 		}()
 
-		// Read logs
-		logData := make([]byte, 1024*1024) // 1MB buffer
-		n, err := logs.Read(logData)
-		if err != nil && err.Error() != "EOF" {
-			_ = s.dockerClient.ContainerRemove(runCtx, containerID, container.RemoveOptions{Force: true})
-			return nil, fmt.Errorf("failed to read container logs: %w", err)
-		}
-
 		// Create result
 		cmdResult := &types.Result{
 			Command: command,
 		}
-		cmdResult.Stdout.Write(logData[:n])
+		var started time.Time
+		if s.config.Transcript {
+			cmdResult.Transcript = types.NewTranscript()
+			started = s.containerStarted(runCtx, containerID)
+		}
 
-		// Set exit code
+		// Demultiplex stdout and stderr from the logs
+		if err := demuxLogs(logs, cmdResult, s.config.Transcript, started); err != nil {
+			telemetry.EndErr(phase, err)
+			s.removeContainer(runCtx, containerID)
+			return nil, fmt.Errorf("failed to read container logs: %w", err)
+		}
+		telemetry.EndErr(phase, nil)
+
+		// Set exit code and classify failures
+		cmdResult.SetExitCode(int(result.StatusCode))
+		var exitErr error
 		if result.StatusCode != 0 {
-			cmdResult.SetExitError(&exec.ExitError{})
+			exitErr = s.exitError(runCtx, containerID, int(result.StatusCode))
 		}
 
-		// Always clean up container manually
-		_ = s.dockerClient.ContainerRemove(runCtx, containerID, container.RemoveOptions{Force: true})
+		// Clean up container manually (AutoRemove would drop the logs)
+		s.removeContainer(runCtx, containerID)
 
+		if exitErr != nil {
+			return cmdResult, errkit.WithMessagef(exitErr, "container exited with code %d", result.StatusCode)
+		}
 		return cmdResult, nil
 	}
 }
 
 // Run executes a command in the Docker container (synchronous execution)
 func (s *SandboxDocker) Run(ctx context.Context, cmd string) (*types.Result, error) {
-	// Parse command into parts
-	cmdParts := strings.Fields(cmd)
-	return s.runCommand(ctx, cmdParts, cmd, false, "")
+	// Parse command into parts with shell quoting rules
+	cmdParts, err := shlex.Split(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(cmdParts) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return s.runCommand(ctx, metrics.EngineCommand, cmdParts, cmd, false, "")
+}
+
+// RunArgs executes the command args[0] with arguments args[1:] in the Docker container
+func (s *SandboxDocker) RunArgs(ctx context.Context, args []string) (*types.Result, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return s.runCommand(ctx, metrics.EngineCommand, args, strings.Join(args, " "), false, "")
 }
 
 // RunScript executes a script in the Docker container
@@ -245,7 +321,7 @@ exec %s
 
 	// Execute the script directly
 	cmdParts := []string{"/bin/sh", "-c", scriptContent}
-	return s.runCommand(ctx, cmdParts, fmt.Sprintf("exec %s", tmpFileName), false, "")
+	return s.runCommand(ctx, interpreter, cmdParts, fmt.Sprintf("exec %s", tmpFileName), false, "")
 }
 
 // Start is not implemented for Docker sandbox as it's stateless
@@ -270,6 +346,43 @@ func (s *SandboxDocker) Clear() error {
 	return nil
 }
 
+// exitError returns the typed error for a container which exited with a non-zero code
+func (s *SandboxDocker) exitError(ctx context.Context, containerID string, code int) error {
+	inspect, err := s.dockerClient.ContainerInspect(ctx, containerID)
+	if err == nil && inspect.State != nil && inspect.State.OOMKilled {
+		return types.ErrOOM
+	}
+	return types.ExitCodeError(code)
+}
+
+// containerStarted returns the time the container started at (zero when unknown)
+func (s *SandboxDocker) containerStarted(ctx context.Context, containerID string) time.Time {
+	inspect, err := s.dockerClient.ContainerInspect(ctx, containerID)
+	if err != nil || inspect.State == nil {
+		return time.Time{}
+	}
+	started, _ := time.Parse(time.RFC3339Nano, inspect.State.StartedAt)
+	return started
+}
+
+// removeContainer force removes the container when Remove is set, tracing the operation
+func (s *SandboxDocker) removeContainer(ctx context.Context, containerID string) {
+	if !s.config.Remove {
+		return
+	}
+	ctx, span := s.tracer.Start(ctx, "sandbox.docker.ContainerRemove", dockerSpanAttributes())
+	err := s.dockerClient.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true})
+	telemetry.EndErr(span, err)
+	if err == nil {
+		s.metrics.ContainerRemoved(telemetry.BackendDocker)
+	}
+}
+
+// dockerSpanAttributes returns the span start options common to docker sandbox spans
+func dockerSpanAttributes() trace.SpanStartOption {
+	return trace.WithAttributes(telemetry.AttrBackend.String(telemetry.BackendDocker))
+}
+
 // isDockerInstalled checks if Docker is installed and available by executing docker info
 func isDockerInstalled(ctx context.Context) (bool, error) {
 	cmd := exec.CommandContext(ctx, "docker", "info")
@@ -280,6 +393,43 @@ func isDockerInstalled(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
+// dockerHostConfig returns the host configuration of containers run with config
+func dockerHostConfig(config *DockerConfiguration) (*container.HostConfig, error) {
+	hostConfig := &container.HostConfig{
+		AutoRemove: false, // Don't auto-remove so we can get logs
+	}
+
+	// Set network configuration
+	if config.NetworkDisabled {
+		hostConfig.NetworkMode = "none"
+	} else if config.NetworkMode != "" {
+		hostConfig.NetworkMode = container.NetworkMode(config.NetworkMode)
+	}
+
+	// Set resource limits if specified
+	if config.Memory != "" {
+		hostConfig.Memory = parseMemoryLimit(config.Memory)
+	}
+	nanoCPUs, err := parseCPULimit(config.CPULimit)
+	if err != nil {
+		return nil, err
+	}
+	hostConfig.NanoCPUs = nanoCPUs
+	return hostConfig, nil
+}
+
+// parseCPULimit parses a cpu limit (e.g., "0.5", "2") to billionths of cpus
+func parseCPULimit(cpus string) (int64, error) {
+	if cpus == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(cpus), 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid cpu limit %q", cpus)
+	}
+	return int64(value * 1e9), nil
+}
+
 // parseMemoryLimit parses memory limit string (e.g., "512m", "1g") to bytes
 func parseMemoryLimit(memory string) int64 {
 	if memory == "" {
@@ -309,9 +459,14 @@ func parseMemoryLimit(memory string) int64 {
 }
 
 // pullImageIfNeeded pulls the Docker image if it doesn't exist locally
-func (s *SandboxDocker) pullImageIfNeeded(ctx context.Context, imageName string) error {
+func (s *SandboxDocker) pullImageIfNeeded(ctx context.Context, imageName string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "sandbox.docker.ImagePull", dockerSpanAttributes())
+	defer func() {
+		telemetry.EndErr(span, err)
+	}()
+
 	// Check if image exists locally
-	_, _, err := s.dockerClient.ImageInspectWithRaw(ctx, imageName)
+	_, _, err = s.dockerClient.ImageInspectWithRaw(ctx, imageName)
 	if err == nil {
 		// Image exists locally, no need to pull
 		return nil
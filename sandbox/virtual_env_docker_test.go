@@ -0,0 +1,31 @@
+package sandbox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerHostConfig(t *testing.T) {
+	hostConfig, err := dockerHostConfig(&DockerConfiguration{Memory: "512m", CPULimit: "0.5", NetworkDisabled: true})
+	require.Nil(t, err)
+	require.Equal(t, int64(512*1024*1024), hostConfig.Memory)
+	require.Equal(t, int64(500_000_000), hostConfig.NanoCPUs)
+	require.Equal(t, "none", string(hostConfig.NetworkMode))
+
+	hostConfig, err = dockerHostConfig(&DockerConfiguration{})
+	require.Nil(t, err)
+	require.Zero(t, hostConfig.NanoCPUs)
+
+	_, err = dockerHostConfig(&DockerConfiguration{CPULimit: "half"})
+	require.NotNil(t, err)
+}
+
+func TestDockerKeepContainer(t *testing.T) {
+	// kept containers are never removed, the docker client is not used
+	s := &SandboxDocker{config: &DockerConfiguration{Remove: false}}
+	require.NotPanics(t, func() {
+		s.removeContainer(context.Background(), "kept")
+	})
+}
@@ -0,0 +1,38 @@
+package gozero
+
+import (
+	"context"
+	"io"
+
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// sandboxUnavailable is the backend reported when Options.Sandbox is set
+// but no sandbox is available
+const sandboxUnavailable = "none"
+
+// evalFunc evaluates src with input (optional) as stdin
+type evalFunc func(ctx context.Context, src, input *Source, args []string) (*types.Result, error)
+
+// sandboxVariables returns the src and input variables as a map
+func sandboxVariables(src, input *Source) map[string]string {
+	env := map[string]string{}
+	for _, source := range []*Source{src, input} {
+		if source == nil {
+			continue
+		}
+		for _, variable := range source.Variables {
+			env[variable.Name] = variable.Value
+		}
+	}
+	return env
+}
+
+// sandboxStdin reads the stdin of a sandboxed evaluation from input (optional)
+func sandboxStdin(input *Source) (string, error) {
+	if input == nil || input.File == nil {
+		return "", nil
+	}
+	data, err := io.ReadAll(input.File)
+	return string(data), err
+}
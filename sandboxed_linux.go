@@ -0,0 +1,165 @@
+//go:build linux
+
+package gozero
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/projectdiscovery/gozero/internal/telemetry"
+	"github.com/projectdiscovery/gozero/sandbox"
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// hostSystemDirs are mounted read-only in bubblewrap sandboxes with HostFilesystem
+var hostSystemDirs = []string{"/usr", "/lib", "/lib64", "/bin", "/sbin"}
+
+// defaultSandbox returns the backend used when Options.Sandbox is set:
+// bubblewrap, then systemd-run. Both run without network access, with
+// a read-only host filesystem and a private /tmp
+func (g *Gozero) defaultSandbox() (string, evalFunc, error) {
+	if _, err := exec.LookPath("bwrap"); err == nil {
+		return telemetry.BackendBubblewrap, g.evalBubblewrap, nil
+	}
+	if _, err := exec.LookPath("systemd-run"); err == nil {
+		return telemetry.BackendSystemd, g.evalSystemd, nil
+	}
+	return sandboxUnavailable, nil, types.BackendUnavailableError(errors.New("neither bubblewrap (bwrap) nor systemd-run is installed"))
+}
+
+// evalBubblewrap evaluates src in a bubblewrap sandbox. Only the source
+// is visible in the sandbox besides the read-only system directories
+func (g *Gozero) evalBubblewrap(ctx context.Context, src, input *Source, args []string) (*types.Result, error) {
+	config := &sandbox.BubblewrapConfiguration{
+		HostFilesystem: true,
+		NewSession:     true,
+		// the host environment is not inherited with CleanEnv
+		ClearEnvironment: g.Options.CleanEnv,
+		TracerProvider:   g.Options.TracerProvider,
+		SuccessCriteria:  g.Options.SuccessCriteria,
+		Retry:            g.Options.Retry,
+	}
+	// engines installed elsewhere (e.g. /opt) are mounted read-only as well
+	for _, engine := range []string{g.Options.engine, resolveSymlinks(g.Options.engine)} {
+		dir := filepath.Dir(engine)
+		bind := sandbox.BindMount{HostPath: dir, SandboxPath: dir}
+		if !underAny(dir, hostSystemDirs) && !slices.Contains(config.ReadOnlySystemBinds, bind) {
+			config.ReadOnlySystemBinds = append(config.ReadOnlySystemBinds, bind)
+		}
+	}
+	bwrap, err := sandbox.NewBubblewrapSandbox(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	scriptDir, script, err := stageSource(os.TempDir(), src)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(scriptDir)
+	}()
+	stdin, err := sandboxStdin(input)
+	if err != nil {
+		return nil, err
+	}
+	return bwrap.ExecuteWithOptions(ctx, &sandbox.BubblewrapCommandOptions{
+		Command:      g.Options.engine,
+		Engine:       g.Options.engine,
+		Args:         append(append(append([]string{}, g.Options.Args...), "/src/"+script), args...),
+		CommandBinds: []sandbox.BindMount{{HostPath: scriptDir, SandboxPath: "/src"}},
+		Chdir:        "/src",
+		Environment:  sandboxVariables(src, input),
+		Stdin:        stdin,
+	})
+}
+
+// evalSystemd evaluates src in a transient systemd unit
+func (g *Gozero) evalSystemd(ctx context.Context, src, input *Source, args []string) (*types.Result, error) {
+	yes := sandbox.Arg{Type: sandbox.Bool, Params: "yes"}
+	systemd, err := sandbox.New(ctx, &sandbox.Configuration{
+		Rules: []sandbox.Rule{
+			{Filter: sandbox.NoNewPrivileges, Arg: yes},
+			{Filter: sandbox.PrivateNetwork, Arg: yes},
+			{Filter: sandbox.PrivateTmp, Arg: yes},
+			{Filter: sandbox.ProtectSystem, Arg: sandbox.Arg{Type: sandbox.Value, Params: "strict"}},
+			{Filter: sandbox.ProtectHome, Arg: sandbox.Arg{Type: sandbox.Value, Params: "read-only"}},
+		},
+		Environment:     sandboxVariables(src, input),
+		SuccessCriteria: g.Options.SuccessCriteria,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the private /tmp of the unit hides the host one, the source is staged
+	// in the (read-only) user cache directory instead
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(cacheDir, "gozero"), 0700); err != nil {
+		return nil, err
+	}
+	scriptDir, script, err := stageSource(filepath.Join(cacheDir, "gozero"), src)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(scriptDir)
+	}()
+	stdin, err := sandboxStdin(input)
+	if err != nil {
+		return nil, err
+	}
+	return types.Retry(ctx, g.Options.Retry, func(ctx context.Context, _ int) (*types.Result, error) {
+		return systemd.(*sandbox.SandboxLinux).ExecuteWithOptions(ctx, &sandbox.SystemdCommandOptions{
+			Command:    g.Options.engine,
+			Args:       append(append(append([]string{}, g.Options.Args...), filepath.Join(scriptDir, script)), args...),
+			Stdin:      stdin,
+			WorkingDir: g.Options.WorkDir,
+		})
+	})
+}
+
+// stageSource copies src to a new directory in dir and returns the directory
+// and the name of the copy, which keeps the extension of src
+func stageSource(dir string, src *Source) (string, string, error) {
+	content, err := src.ReadAll()
+	if err != nil {
+		return "", "", err
+	}
+	scriptDir, err := os.MkdirTemp(dir, "gozero-script-*")
+	if err != nil {
+		return "", "", err
+	}
+	script := "script" + filepath.Ext(src.Filename)
+	if err := os.WriteFile(filepath.Join(scriptDir, script), content, 0644); err != nil {
+		_ = os.RemoveAll(scriptDir)
+		return "", "", err
+	}
+	return scriptDir, script, nil
+}
+
+// resolveSymlinks returns the target of path, or path when it cannot be resolved
+func resolveSymlinks(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// underAny reports whether path is one of dirs or inside one of them
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
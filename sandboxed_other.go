@@ -0,0 +1,16 @@
+//go:build !linux
+
+package gozero
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// defaultSandbox returns the backend used when Options.Sandbox is set,
+// no default sandbox is available on this platform
+func (g *Gozero) defaultSandbox() (string, evalFunc, error) {
+	return sandboxUnavailable, nil, types.BackendUnavailableError(fmt.Errorf("no default sandbox on %s", runtime.GOOS))
+}
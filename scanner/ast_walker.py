@@ -0,0 +1,138 @@
+# Reports risky constructs of the python source read from stdin as a JSON list.
+# usage: python ast_walker.py [workspace]
+import ast
+import json
+import os
+import sys
+
+NETWORK_MODULES = {
+    "aiohttp", "ftplib", "http", "httpx", "paramiko", "requests", "smtplib",
+    "socket", "socketserver", "telnetlib", "urllib", "urllib2", "urllib3",
+    "websocket", "websockets", "xmlrpc",
+}
+PROCESS_MODULES = {"subprocess", "pty", "multiprocessing"}
+PROCESS_CALLS = {
+    "os.system", "os.popen", "os.fork", "os.forkpty", "os.posix_spawn", "os.posix_spawnp",
+    "os.execl", "os.execle", "os.execlp", "os.execlpe", "os.execv", "os.execve", "os.execvp", "os.execvpe",
+    "os.spawnl", "os.spawnle", "os.spawnlp", "os.spawnlpe", "os.spawnv", "os.spawnve", "os.spawnvp", "os.spawnvpe",
+    "pty.spawn",
+}
+EVAL_CALLS = {"eval", "exec", "compile", "__import__", "importlib.import_module"}
+SENSITIVE_PATHS = ("/etc/", "~/.ssh", "/.ssh/", "~/.aws", "/.aws/")
+SAFE_WRITE_PATHS = ("/dev/null", "/dev/stdout", "/dev/stderr", "/dev/fd/", "/tmp/")
+
+
+def sensitive(path):
+    return path == "/etc" or any(p in path for p in SENSITIVE_PATHS)
+
+
+class Walker(ast.NodeVisitor):
+    def __init__(self, workspace):
+        self.workspace = workspace
+        self.aliases = {}
+        self.findings = []
+        self.reported = set()
+
+    def report(self, node, rule, category, severity, message):
+        self.reported.add(id(node))
+        self.findings.append({
+            "rule": rule,
+            "category": category,
+            "severity": severity,
+            "line": getattr(node, "lineno", 0),
+            "column": getattr(node, "col_offset", -1) + 1,
+            "message": message,
+        })
+
+    def imported(self, node, module):
+        root = module.split(".")[0]
+        if root in NETWORK_MODULES:
+            self.report(node, "network-import", "network", "medium", "imports network module %s" % module)
+        elif root in PROCESS_MODULES:
+            self.report(node, "process-import", "process", "medium", "imports process module %s" % module)
+
+    def visit_Import(self, node):
+        for alias in node.names:
+            if alias.asname:
+                self.aliases[alias.asname] = alias.name
+            else:
+                # import os.path binds os
+                root = alias.name.split(".")[0]
+                self.aliases[root] = root
+            self.imported(node, alias.name)
+        self.generic_visit(node)
+
+    def visit_ImportFrom(self, node):
+        module = node.module or ""
+        for alias in node.names:
+            self.aliases[alias.asname or alias.name] = module + "." + alias.name
+        if node.level == 0:
+            self.imported(node, module)
+        self.generic_visit(node)
+
+    def name(self, node):
+        # dotted name of the called function with import aliases resolved
+        parts = []
+        while isinstance(node, ast.Attribute):
+            parts.append(node.attr)
+            node = node.value
+        if not isinstance(node, ast.Name):
+            return ""
+        parts.append(self.aliases.get(node.id, node.id))
+        return ".".join(reversed(parts))
+
+    def visit_Call(self, node):
+        name = self.name(node.func)
+        if name in PROCESS_CALLS or name.startswith("subprocess."):
+            self.report(node, "process-spawn", "process", "high", "spawns a process with %s" % name)
+        elif name in EVAL_CALLS or name.startswith("builtins.") and name[9:] in EVAL_CALLS:
+            self.report(node, "dynamic-eval", "eval", "high", "evaluates dynamic code with %s" % name)
+        elif name in ("open", "io.open", "builtins.open") and node.args:
+            self.visit_open(node)
+        self.generic_visit(node)
+
+    def visit_open(self, node):
+        path = node.args[0]
+        if not isinstance(path, ast.Constant) or not isinstance(path.value, str):
+            return
+        mode = "r"
+        if len(node.args) > 1 and isinstance(node.args[1], ast.Constant):
+            mode = str(node.args[1].value)
+        for keyword in node.keywords:
+            if keyword.arg == "mode" and isinstance(keyword.value, ast.Constant):
+                mode = str(keyword.value.value)
+        writes = any(c in mode for c in "wax+")
+        if writes and self.outside_workspace(path.value):
+            self.report(path, "write-outside-workspace", "file-write", "medium", "writes %s outside the workspace" % path.value)
+        elif sensitive(path.value):
+            self.report(path, "sensitive-read", "sensitive-read", "high", "reads sensitive path %s" % path.value)
+
+    def outside_workspace(self, path):
+        # paths are normalized first so /tmp/../root is not considered under /tmp
+        path = os.path.normpath(os.path.expanduser(path))
+        for safe in SAFE_WRITE_PATHS:
+            if path == safe.rstrip("/") or (safe.endswith("/") and path.startswith(safe)):
+                return False
+        if not os.path.isabs(path):
+            return path == ".." or path.startswith("../")
+        return not (self.workspace and path.startswith(self.workspace.rstrip("/") + "/"))
+
+    def visit_Constant(self, node):
+        if isinstance(node.value, str) and id(node) not in self.reported and sensitive(node.value):
+            self.report(node, "sensitive-path", "sensitive-read", "medium", "references sensitive path %s" % node.value)
+
+
+def main():
+    workspace = sys.argv[1] if len(sys.argv) > 1 else ""
+    source = sys.stdin.buffer.read() if hasattr(sys.stdin, "buffer") else sys.stdin.read()
+    try:
+        tree = ast.parse(source)
+    except (SyntaxError, ValueError) as e:
+        sys.stderr.write("%s\n" % e)
+        sys.exit(2)
+    walker = Walker(workspace)
+    walker.visit(tree)
+    json.dump(walker.findings, sys.stdout)
+
+
+main()
@@ -0,0 +1,219 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// patternRule reports lines matching re. The first submatch is the subject
+// of the message (the whole match when the pattern has no submatch)
+type patternRule struct {
+	rule     string
+	category string
+	severity Severity
+	re       *regexp.Regexp
+	message  string
+	// write marks rules whose subject is a written path,
+	// only reported when it is outside the workspace
+	write bool
+}
+
+// sensitivePath matches the paths reported as sensitive
+const sensitivePath = `(?:/etc\b|~/\.ssh|/\.ssh\b|\$HOME/\.ssh|~/\.aws|/\.aws\b|\$HOME/\.aws)`
+
+// safeWritePaths can be written from anywhere
+var safeWritePaths = []string{"/dev/null", "/dev/stdout", "/dev/stderr", "/dev/fd/", "/tmp/"}
+
+// rules are evaluated in order and a line is reported at most once per category,
+// so more specific rules come first
+var (
+	pythonRules = []patternRule{
+		{
+			rule: "process-spawn", category: CategoryProcess, severity: SeverityHigh,
+			re:      regexp.MustCompile(`\b(subprocess\.\w+|os\.(?:system|popen|fork\w*|exec\w+|spawn\w+|posix_spawnp?)|pty\.spawn)\s*\(`),
+			message: "spawns a process with %s",
+		},
+		{
+			rule: "process-import", category: CategoryProcess, severity: SeverityMedium,
+			re:      regexp.MustCompile(`^\s*(?:import|from)\s+(subprocess|pty|multiprocessing)\b`),
+			message: "imports process module %s",
+		},
+		{
+			rule: "network-import", category: CategoryNetwork, severity: SeverityMedium,
+			re:      regexp.MustCompile(`^\s*(?:import|from)\s+((?:socket|socketserver|urllib\d?|requests|http|httpx|aiohttp|ftplib|smtplib|telnetlib|paramiko|websockets?|xmlrpc)\b[\w.]*)`),
+			message: "imports network module %s",
+		},
+		{
+			rule: "dynamic-eval", category: CategoryEval, severity: SeverityHigh,
+			re:      regexp.MustCompile(`(?:^|[^.\w])(eval|exec|compile|__import__)\s*\(`),
+			message: "evaluates dynamic code with %s",
+		},
+		{
+			rule: "write-outside-workspace", category: CategoryFileWrite, severity: SeverityMedium,
+			re:      regexp.MustCompile(`\bopen\(\s*['"]([^'"]+)['"]\s*,\s*(?:mode\s*=\s*)?['"][^'"]*[wax+]`),
+			message: "writes %s outside the workspace", write: true,
+		},
+		{
+			rule: "sensitive-read", category: CategorySensitiveRead, severity: SeverityHigh,
+			re:      regexp.MustCompile(`\bopen\(\s*['"]([^'"]*` + sensitivePath + `[^'"]*)['"]`),
+			message: "reads sensitive path %s",
+		},
+		{
+			rule: "sensitive-path", category: CategorySensitiveRead, severity: SeverityMedium,
+			re:      regexp.MustCompile(`['"]([^'"]*` + sensitivePath + `[^'"]*)['"]`),
+			message: "references sensitive path %s",
+		},
+	}
+
+	javascriptRules = []patternRule{
+		{
+			rule: "process-spawn", category: CategoryProcess, severity: SeverityHigh,
+			// exec is only matched on child_process or called bare (destructured),
+			// not as a method (e.g. RegExp.prototype.exec)
+			re: regexp.MustCompile(`\b((?:child_process\.)?(?:execSync|execFile|execFileSync|spawn|spawnSync)|child_process\.exec|Deno\.(?:run|Command)|Bun\.spawn(?:Sync)?)\s*\(` +
+				`|(?:^|[^.\w$])(exec)\s*\(|require\(\s*['"](?:node:)?child_process['"]\s*\)\.(exec)\s*\(`),
+			message: "spawns a process with %s",
+		},
+		{
+			rule: "process-import", category: CategoryProcess, severity: SeverityMedium,
+			re:      regexp.MustCompile(`['"](?:node:)?(child_process|cluster|worker_threads)['"]`),
+			message: "imports process module %s",
+		},
+		{
+			rule: "network-import", category: CategoryNetwork, severity: SeverityMedium,
+			re:      regexp.MustCompile(`(?:require\s*\(\s*|from\s+|import\s*\(\s*)['"](?:node:)?(https?|http2|net|dgram|tls|dns|axios|node-fetch|ws)['"]`),
+			message: "imports network module %s",
+		},
+		{
+			rule: "network-call", category: CategoryNetwork, severity: SeverityMedium,
+			re:      regexp.MustCompile(`(?:^|[^.\w])(fetch|XMLHttpRequest|WebSocket)\s*\(`),
+			message: "uses network api %s",
+		},
+		{
+			rule: "dynamic-eval", category: CategoryEval, severity: SeverityHigh,
+			re:      regexp.MustCompile(`(?:^|[^.\w])(eval|Function)\s*\(|\b(vm\.(?:run\w*|compileFunction)|new\s+vm\.Script)\b`),
+			message: "evaluates dynamic code with %s",
+		},
+		{
+			rule: "write-outside-workspace", category: CategoryFileWrite, severity: SeverityMedium,
+			re:      regexp.MustCompile("\\bfs\\.(?:promises\\.)?(?:writeFile|writeFileSync|appendFile|appendFileSync|createWriteStream|mkdir|mkdirSync|rm|rmSync|unlink|unlinkSync)\\s*\\(\\s*['\"`]([^'\"`]+)['\"`]"),
+			message: "writes %s outside the workspace", write: true,
+		},
+		{
+			rule: "sensitive-read", category: CategorySensitiveRead, severity: SeverityHigh,
+			re:      regexp.MustCompile("\\bfs\\.(?:promises\\.)?(?:readFile|readFileSync|createReadStream|readdir|readdirSync)\\s*\\(\\s*['\"`]([^'\"`]*" + sensitivePath + "[^'\"`]*)['\"`]"),
+			message: "reads sensitive path %s",
+		},
+		{
+			rule: "sensitive-path", category: CategorySensitiveRead, severity: SeverityMedium,
+			re:      regexp.MustCompile("['\"`]([^'\"`]*" + sensitivePath + "[^'\"`]*)['\"`]"),
+			message: "references sensitive path %s",
+		},
+	}
+
+	shellRules = []patternRule{
+		{
+			rule: "network-tool", category: CategoryNetwork, severity: SeverityMedium,
+			re:      regexp.MustCompile("(?:^|[\\s;|&(`])(curl|wget|nc|ncat|netcat|telnet|ssh|scp|sftp|ftp|socat|rsync)(?:\\s|$)"),
+			message: "uses network tool %s",
+		},
+		{
+			rule: "network-device", category: CategoryNetwork, severity: SeverityMedium,
+			re:      regexp.MustCompile(`(/dev/(?:tcp|udp)/[^\s;|&)]+)`),
+			message: "opens network connection %s",
+		},
+		{
+			rule: "dynamic-eval", category: CategoryEval, severity: SeverityHigh,
+			re:      regexp.MustCompile(`(?:^|[\s;|&(])(eval|(?:ba|z|da|k)?sh\s+-c)(?:\s|$)|\|\s*((?:ba|z|da|k)?sh)\b`),
+			message: "evaluates dynamic code with %s",
+		},
+		{
+			rule: "detached-process", category: CategoryProcess, severity: SeverityLow,
+			re:      regexp.MustCompile(`(?:^|[\s;|&(])(nohup|setsid|disown)\b`),
+			message: "detaches a process with %s",
+		},
+		{
+			rule: "write-outside-workspace", category: CategoryFileWrite, severity: SeverityMedium,
+			re:      regexp.MustCompile(`(?:(?:^|[^<])>>?|\btee\s+(?:-a\s+)?|\bdd\s+.*\bof=)\s*((?:/|~|\$HOME)[^\s;|&)]*)`),
+			message: "writes %s outside the workspace", write: true,
+		},
+		{
+			rule: "sensitive-read", category: CategorySensitiveRead, severity: SeverityHigh,
+			re:      regexp.MustCompile(`(?:^|[\s'"=<])(` + sensitivePath + `[^\s'";|&)]*)`),
+			message: "reads sensitive path %s",
+		},
+	}
+)
+
+// scanPatterns analyzes src line by line with rules. Lines starting with
+// the comment prefix are skipped
+func (s *Scanner) scanPatterns(rules []patternRule, comment string, src []byte) []Finding {
+	var findings []Finding
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(text), comment) {
+			continue
+		}
+		reported := map[string]bool{}
+		for _, rule := range rules {
+			if reported[rule.category] {
+				continue
+			}
+			loc := rule.re.FindStringSubmatchIndex(text)
+			if loc == nil {
+				continue
+			}
+			// the subject is the first submatch which matched
+			start, end := loc[0], loc[1]
+			for i := 2; i < len(loc); i += 2 {
+				if loc[i] >= 0 {
+					start, end = loc[i], loc[i+1]
+					break
+				}
+			}
+			subject := text[start:end]
+			if rule.write && !s.outsideWorkspace(subject) {
+				continue
+			}
+			reported[rule.category] = true
+			findings = append(findings, Finding{
+				Rule:     rule.rule,
+				Category: rule.category,
+				Severity: rule.severity,
+				Line:     line,
+				Column:   start + 1,
+				Message:  fmt.Sprintf(rule.message, subject),
+			})
+		}
+	}
+	return findings
+}
+
+// outsideWorkspace reports whether p is outside the workspace.
+// Paths of sources are slash separated whatever the platform
+func (s *Scanner) outsideWorkspace(p string) bool {
+	if strings.HasPrefix(p, "~") || strings.HasPrefix(p, "$HOME") {
+		return true
+	}
+	// paths are cleaned first so /tmp/../root is not considered under /tmp
+	p = path.Clean(p)
+	for _, safe := range safeWritePaths {
+		if p == strings.TrimSuffix(safe, "/") || (strings.HasSuffix(safe, "/") && strings.HasPrefix(p, safe)) {
+			return false
+		}
+	}
+	if !path.IsAbs(p) {
+		return p == ".." || strings.HasPrefix(p, "../")
+	}
+	if s.Workspace == "" {
+		return true
+	}
+	workspace := path.Clean(s.Workspace)
+	return p != workspace && !strings.HasPrefix(p, strings.TrimSuffix(workspace, "/")+"/")
+}
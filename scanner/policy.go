@@ -0,0 +1,35 @@
+package scanner
+
+import (
+	"context"
+	"os"
+
+	"github.com/projectdiscovery/gozero/policy"
+)
+
+// Rule returns a policy rule rejecting sources with findings of severity or
+// above. The language of the source is selected from the engine, sources of
+// other languages are not scanned
+func (s *Scanner) Rule(severity Severity) policy.Rule {
+	return policy.RuleFunc(func(req *policy.Request) []policy.Violation {
+		lang, ok := LanguageOf(req.EngineName())
+		if !ok {
+			return nil
+		}
+		src, err := os.ReadFile(req.Filename)
+		if err != nil {
+			return []policy.Violation{{Rule: "risk-scan", Message: "could not read source: " + err.Error()}}
+		}
+		findings, err := s.Scan(context.Background(), lang, src)
+		if err != nil {
+			return []policy.Violation{{Rule: "risk-scan", Message: err.Error()}}
+		}
+		var violations []policy.Violation
+		for _, f := range findings {
+			if f.Severity >= severity {
+				violations = append(violations, policy.Violation{Rule: "risk-scan", Message: f.String()})
+			}
+		}
+		return violations
+	})
+}
@@ -0,0 +1,47 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"os/exec"
+)
+
+// astWalker reports the findings of the python source read from stdin as json
+//
+//go:embed ast_walker.py
+var astWalker string
+
+// scanPythonAST analyzes src with the ast module of the python interpreter.
+// It returns false when the interpreter is not installed or could not parse src
+func (s *Scanner) scanPythonAST(ctx context.Context, src []byte) ([]Finding, bool) {
+	python := s.Python
+	if python == "" {
+		python = "python3"
+	}
+	path, err := exec.LookPath(python)
+	if err != nil {
+		return nil, false
+	}
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	// -E and -s ignore PYTHON* variables and user site-packages
+	cmd := exec.CommandContext(ctx, path, "-E", "-s", "-c", astWalker, s.Workspace)
+	cmd.Stdin = bytes.NewReader(src)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, false
+	}
+	var findings []Finding
+	if err := json.Unmarshal(stdout.Bytes(), &findings); err != nil {
+		return nil, false
+	}
+	return findings, true
+}
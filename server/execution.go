@@ -0,0 +1,103 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// Status is the state of an execution
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Finished reports whether the status is final
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Execution modes of a request
+const (
+	ModeAsync = "async"
+	ModeSync  = "sync"
+)
+
+// Duration is a time.Duration encoded as a string (e.g. "30s") in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return fmt.Errorf("negative duration %q", text)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Limits are the resource limits of an execution
+type Limits struct {
+	Image   string   `json:"image,omitempty"`
+	Memory  string   `json:"memory,omitempty"`
+	CPUs    string   `json:"cpus,omitempty"`
+	Network string   `json:"network,omitempty"`
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// Profile is a named sandbox configuration executions can select.
+// The limits of a request can only tighten the limits of the profile:
+// memory, cpus and timeout may be lowered and the network disabled
+type Profile struct {
+	// Backend is one of none, docker, bwrap or systemd
+	Backend string `json:"backend"`
+	Limits  Limits `json:"limits"`
+	// AllowImage lets requests choose the image
+	AllowImage bool `json:"allow_image,omitempty"`
+	// AllowNetwork lets requests choose the network
+	AllowNetwork bool `json:"allow_network,omitempty"`
+}
+
+// ExecutionRequest is the body of POST /v1/executions
+type ExecutionRequest struct {
+	Source    string            `json:"source"`
+	Language  string            `json:"language"`
+	Input     *string           `json:"input,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Profile   string            `json:"profile,omitempty"`
+	Limits    Limits            `json:"limits,omitempty"`
+	// Mode is async (default) or sync
+	Mode string `json:"mode,omitempty"`
+}
+
+// Execution is the state and result of a submitted execution
+type Execution struct {
+	ID         string        `json:"id"`
+	Status     Status        `json:"status"`
+	Profile    string        `json:"profile"`
+	CreatedAt  time.Time     `json:"created_at"`
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Result     *types.Result `json:"result,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Event types sent on the events stream
+const (
+	EventStdout = "stdout"
+	EventStderr = "stderr"
+	EventDone   = "done"
+)
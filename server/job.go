@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gozero/internal/runner"
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// event is an output chunk or the completion of a job
+type event struct {
+	typ  string
+	data []byte
+}
+
+// job is a submitted execution. The output of the execution is kept as
+// events so that subscribers joining late receive it from the start
+type job struct {
+	spec    *runner.Spec
+	timeout time.Duration
+	parent  context.Context // server context, done on shutdown
+	ctx     context.Context
+	cancel  context.CancelFunc
+	persist func(record *Record)
+
+	mu       sync.Mutex
+	record   Record
+	canceled bool
+	events   []event
+	notify   chan struct{} // closed and replaced when an event is added
+	done     chan struct{}
+}
+
+// newJob creates the job of a queued record. persist is called with the
+// record on every state change
+func newJob(ctx context.Context, record *Record, spec *runner.Spec, timeout time.Duration, persist func(record *Record)) *job {
+	j := &job{
+		spec:    spec,
+		timeout: timeout,
+		parent:  ctx,
+		persist: persist,
+		record:  *record,
+		notify:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	j.ctx, j.cancel = context.WithCancel(ctx)
+	spec.Stdout = &eventWriter{job: j, typ: EventStdout}
+	spec.Stderr = &eventWriter{job: j, typ: EventStderr}
+	return j
+}
+
+// finishedJob creates the job of a finished record
+func finishedJob(record *Record) *job {
+	j := &job{
+		record: *record,
+		notify: make(chan struct{}),
+		done:   make(chan struct{}),
+		events: []event{{typ: EventDone}},
+		cancel: func() {},
+	}
+	close(j.done)
+	return j
+}
+
+// snapshot returns a copy of the execution state
+func (j *job) snapshot() Execution {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.record.Execution
+}
+
+// run executes the job unless it was canceled while queued
+func (j *job) run() {
+	j.mu.Lock()
+	if j.record.Execution.Status.Finished() {
+		j.mu.Unlock()
+		return
+	}
+	now := time.Now().UTC()
+	j.record.Execution.Status = StatusRunning
+	j.record.Execution.StartedAt = &now
+	j.record.Attempts++
+	j.persist(&j.record)
+	j.mu.Unlock()
+
+	ctx := j.ctx
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+	res, err := runner.Run(ctx, j.spec)
+	j.finish(res, err)
+}
+
+// finish records the outcome of the job and notifies subscribers
+func (j *job) finish(res *types.Result, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finishLocked(res, err)
+}
+
+func (j *job) finishLocked(res *types.Result, err error) {
+	execution := &j.record.Execution
+	if execution.Status.Finished() {
+		return
+	}
+	now := time.Now().UTC()
+	execution.FinishedAt = &now
+	execution.Result = res
+	// executions stopped by a shutdown keep their persisted state and are recovered on restart
+	interrupted := !j.canceled && j.parent.Err() != nil
+	switch {
+	case interrupted:
+		execution.Status = StatusFailed
+		execution.Error = ErrInterrupted.Error()
+	case j.canceled:
+		execution.Status = StatusCanceled
+		execution.Error = context.Canceled.Error()
+	case err != nil:
+		execution.Status = StatusFailed
+		execution.Error = err.Error()
+	default:
+		execution.Status = StatusSucceeded
+	}
+	if !interrupted {
+		j.persist(&j.record)
+	}
+	j.cancel()
+	j.addEventLocked(event{typ: EventDone})
+	close(j.done)
+}
+
+// cancelJob cancels a queued or running job and returns false when it already finished
+func (j *job) cancelJob() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.record.Execution.Status.Finished() {
+		return false
+	}
+	j.canceled = true
+	j.cancel()
+	if j.record.Execution.Status == StatusQueued {
+		// a queued job is skipped by the worker picking it up
+		j.finishLocked(nil, context.Canceled)
+	}
+	return true
+}
+
+// finishedBefore reports whether the job finished before since
+func (j *job) finishedBefore(since time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	finishedAt := j.record.Execution.FinishedAt
+	return finishedAt != nil && finishedAt.Before(since)
+}
+
+func (j *job) addEventLocked(e event) {
+	j.events = append(j.events, e)
+	close(j.notify)
+	j.notify = make(chan struct{})
+}
+
+// eventsFrom returns the events after the first n ones and a channel
+// closed when more events are available
+func (j *job) eventsFrom(n int) ([]event, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if n > len(j.events) {
+		n = len(j.events)
+	}
+	return j.events[n:len(j.events):len(j.events)], j.notify
+}
+
+// eventWriter records output written by the execution as events
+type eventWriter struct {
+	job *job
+	typ string
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	w.job.mu.Lock()
+	defer w.job.mu.Unlock()
+	if w.job.record.Execution.Status.Finished() {
+		return 0, errors.New("execution already finished")
+	}
+	w.job.addEventLocked(event{typ: w.typ, data: append([]byte(nil), p...)})
+	return len(p), nil
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// queue is the FIFO of jobs waiting for a worker
+type queue struct {
+	mu      sync.Mutex
+	pending []*job
+	notify  chan struct{} // closed and replaced when a job is pushed
+}
+
+func newQueue() *queue {
+	return &queue{notify: make(chan struct{})}
+}
+
+// push appends j unless limit (> 0) jobs are already waiting
+func (q *queue) push(j *job, limit int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if limit > 0 && len(q.pending) >= limit {
+		return ErrQueueFull
+	}
+	q.pending = append(q.pending, j)
+	close(q.notify)
+	q.notify = make(chan struct{})
+	return nil
+}
+
+// pop waits for the next job until ctx is done
+func (q *queue) pop(ctx context.Context) (*job, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.pending) > 0 {
+			j := q.pending[0]
+			q.pending[0] = nil
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+			return j, true
+		}
+		notify := q.notify
+		q.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// drain removes and returns all waiting jobs
+func (q *queue) drain() []*job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := q.pending
+	q.pending = nil
+	return pending
+}
+
+// len returns the number of waiting jobs
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
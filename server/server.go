@@ -0,0 +1,633 @@
+// server package exposes gozero executions over an HTTP API.
+//
+// Executions are submitted with POST /v1/executions and run by a bounded
+// pool of workers. They can be awaited (sync mode), polled, canceled and
+// their output followed live as server-sent events.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gozero/internal/runner"
+	"github.com/projectdiscovery/gozero/types"
+)
+
+// DefaultProfile is the profile name used when a request does not select one
+const DefaultProfile = "default"
+
+const (
+	defaultQueueSize = 100
+	defaultRetention = time.Hour
+	defaultAttempts  = 3
+	maxRequestBytes  = 10 << 20
+)
+
+var (
+	// ErrQueueFull is returned when the execution queue is full
+	ErrQueueFull = errors.New("execution queue is full")
+	// ErrServerClosed is returned when submitting to a closed server
+	ErrServerClosed = errors.New("server closed")
+	// ErrNoDefaultProfile is returned by New when no default profile is configured
+	ErrNoDefaultProfile = errors.New("no default profile configured")
+)
+
+// Config is the configuration of the execution server
+type Config struct {
+	// Tokens accepted as bearer tokens. Authentication is disabled when empty
+	Tokens []string
+	// MaxConcurrency is the maximum number of executions running at once (defaults to the number of CPUs)
+	MaxConcurrency int
+	// QueueSize is the maximum number of executions waiting to run (defaults to 100)
+	QueueSize int
+	// Profiles are the sandbox profiles requests can select, a "default" profile
+	// is required. Running executions without sandbox must be configured
+	// explicitly with a profile using the none backend
+	Profiles map[string]Profile
+	// MaxTimeout caps the timeout of executions and is used when a request sets none (0 disables it)
+	MaxTimeout time.Duration
+	// Retention is how long finished executions are kept (defaults to 1h)
+	Retention time.Duration
+	// Store persists executions across restarts (nil keeps them in memory only).
+	// The store is owned by the caller and must be closed after the server
+	Store Store
+	// Recovery decides what happens to executions interrupted by a restart
+	Recovery RecoveryPolicy
+	// MaxAttempts is the number of times an interrupted execution is started
+	// before it is marked as failed (defaults to 3)
+	MaxAttempts int
+	// ErrorLog receives errors which cannot be returned to a client (defaults to the log package logger)
+	ErrorLog *log.Logger
+}
+
+// Server runs executions submitted over HTTP
+type Server struct {
+	config  Config
+	mux     *http.ServeMux
+	ctx     context.Context
+	cancel  context.CancelFunc
+	queue   *queue
+	workers sync.WaitGroup
+
+	mu     sync.RWMutex
+	jobs   map[string]*job
+	closed bool
+}
+
+// New creates a server and starts its workers
+func New(config Config) (*Server, error) {
+	if config.MaxConcurrency <= 0 {
+		config.MaxConcurrency = runtime.NumCPU()
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaultRetention
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultAttempts
+	}
+	if config.ErrorLog == nil {
+		config.ErrorLog = log.Default()
+	}
+	profiles := make(map[string]Profile, len(config.Profiles))
+	for name, profile := range config.Profiles {
+		if !validBackend(profile.Backend) {
+			return nil, fmt.Errorf("profile %q: unknown backend %q", name, profile.Backend)
+		}
+		profiles[name] = profile
+	}
+	if _, ok := profiles[DefaultProfile]; !ok {
+		return nil, ErrNoDefaultProfile
+	}
+	config.Profiles = profiles
+
+	s := &Server{
+		config: config,
+		mux:    http.NewServeMux(),
+		queue:  newQueue(),
+		jobs:   make(map[string]*job),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	if err := s.recover(); err != nil {
+		s.cancel()
+		return nil, fmt.Errorf("could not recover executions: %w", err)
+	}
+	s.mux.HandleFunc("POST /v1/executions", s.handleSubmit)
+	s.mux.HandleFunc("GET /v1/executions/{id}", s.handleGet)
+	s.mux.HandleFunc("POST /v1/executions/{id}/cancel", s.handleCancel)
+	s.mux.HandleFunc("GET /v1/executions/{id}/events", s.handleEvents)
+
+	for i := 0; i < config.MaxConcurrency; i++ {
+		s.workers.Add(1)
+		go s.worker()
+	}
+	s.workers.Add(1)
+	go s.janitor()
+	return s, nil
+}
+
+// recover loads the executions of the store, queueing the unfinished ones
+// again according to the recovery policy
+func (s *Server) recover() error {
+	if s.config.Store == nil {
+		return nil
+	}
+	records, err := s.config.Store.Load()
+	if err != nil {
+		return err
+	}
+	expired := time.Now().Add(-s.config.Retention)
+	for _, record := range records {
+		execution := &record.Execution
+		if execution.Status.Finished() {
+			if execution.FinishedAt != nil && execution.FinishedAt.Before(expired) {
+				if err := s.config.Store.Delete(execution.ID); err != nil {
+					return err
+				}
+				continue
+			}
+			s.jobs[execution.ID] = finishedJob(record)
+			continue
+		}
+
+		var recoverErr error
+		if execution.Status == StatusRunning {
+			if s.config.Recovery == RecoverFail || record.Attempts >= s.config.MaxAttempts {
+				recoverErr = ErrInterrupted
+			}
+		}
+		var spec *runner.Spec
+		var timeout time.Duration
+		if recoverErr == nil {
+			// the configuration may have changed since the execution was submitted
+			_, spec, timeout, recoverErr = s.prepare(&record.Request)
+		}
+		if recoverErr != nil {
+			now := time.Now().UTC()
+			execution.Status = StatusFailed
+			execution.Error = recoverErr.Error()
+			execution.FinishedAt = &now
+			if err := s.config.Store.Save(record); err != nil {
+				return err
+			}
+			s.jobs[execution.ID] = finishedJob(record)
+			continue
+		}
+
+		execution.Status = StatusQueued
+		execution.StartedAt = nil
+		j := newJob(s.ctx, record, spec, timeout, s.persist)
+		// recovered executions are not subject to the queue size
+		_ = s.queue.push(j, 0)
+		s.jobs[execution.ID] = j
+	}
+	return nil
+}
+
+// persist saves the record to the store, if any
+func (s *Server) persist(record *Record) {
+	if s.config.Store == nil {
+		return
+	}
+	if err := s.config.Store.Save(record); err != nil {
+		s.config.ErrorLog.Printf("gozero: could not persist execution %s: %v", record.Execution.ID, err)
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gozero"`)
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close stops all executions and waits for the workers to exit. Executions
+// which did not finish are left in the store to be recovered on restart
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	s.cancel()
+	s.workers.Wait()
+	// release the clients waiting for the executions left in the queue
+	for _, j := range s.queue.drain() {
+		j.finish(nil, ErrInterrupted)
+	}
+	return nil
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if len(s.config.Tokens) == 0 {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	valid := false
+	for _, expected := range s.config.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+func (s *Server) worker() {
+	defer s.workers.Done()
+	for {
+		j, ok := s.queue.pop(s.ctx)
+		if !ok {
+			return
+		}
+		j.run()
+	}
+}
+
+// janitor periodically removes expired executions
+func (s *Server) janitor() {
+	defer s.workers.Done()
+	ticker := time.NewTicker(min(max(s.config.Retention/10, time.Second), time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.pruneLocked()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// prepare validates the request and returns its profile, runner spec and timeout
+func (s *Server) prepare(req *ExecutionRequest) (string, *runner.Spec, time.Duration, error) {
+	profileName := req.Profile
+	if profileName == "" {
+		profileName = DefaultProfile
+	}
+	profile, ok := s.config.Profiles[profileName]
+	if !ok {
+		return "", nil, 0, badRequest(fmt.Errorf("unknown profile %q", profileName))
+	}
+	if req.Language == "" {
+		return "", nil, 0, badRequest(errors.New("language is required"))
+	}
+	if req.Mode != "" && req.Mode != ModeAsync && req.Mode != ModeSync {
+		return "", nil, 0, badRequest(fmt.Errorf("unknown mode %q", req.Mode))
+	}
+
+	limits, err := mergeLimits(profile, req.Limits)
+	if err != nil {
+		return "", nil, 0, badRequest(fmt.Errorf("profile %q: %w", profileName, err))
+	}
+	spec := &runner.Spec{
+		Language: req.Language,
+		Source:   []byte(req.Source),
+		Args:     req.Args,
+		Backend:  profile.Backend,
+		Limits: runner.Limits{
+			Image:   limits.Image,
+			Memory:  limits.Memory,
+			CPUs:    limits.CPUs,
+			Network: limits.Network,
+		},
+	}
+	if req.Input != nil {
+		spec.Stdin = []byte(*req.Input)
+	}
+	names := make([]string, 0, len(req.Variables))
+	for name := range req.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		spec.Variables = append(spec.Variables, types.Variable{Name: name, Value: req.Variables[name]})
+	}
+	if err := spec.Validate(); err != nil {
+		return "", nil, 0, badRequest(err)
+	}
+	timeout := time.Duration(limits.Timeout)
+	if s.config.MaxTimeout > 0 && (timeout == 0 || timeout > s.config.MaxTimeout) {
+		timeout = s.config.MaxTimeout
+	}
+	return profileName, spec, timeout, nil
+}
+
+// submit validates the request, persists it and queues a job for it
+func (s *Server) submit(req *ExecutionRequest) (*job, error) {
+	profileName, spec, timeout, err := s.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	record := &Record{
+		Request: *req,
+		Execution: Execution{
+			ID:        id,
+			Status:    StatusQueued,
+			Profile:   profileName,
+			CreatedAt: time.Now().UTC(),
+		},
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrServerClosed
+	}
+	if s.queue.len() >= s.config.QueueSize {
+		return nil, ErrQueueFull
+	}
+	if s.config.Store != nil {
+		if err := s.config.Store.Save(record); err != nil {
+			return nil, fmt.Errorf("could not persist execution: %w", err)
+		}
+	}
+	j := newJob(s.ctx, record, spec, timeout, s.persist)
+	if err := s.queue.push(j, s.config.QueueSize); err != nil {
+		s.deleteRecord(id)
+		return nil, err
+	}
+	s.pruneLocked()
+	s.jobs[id] = j
+	return j, nil
+}
+
+// pruneLocked removes executions finished longer than the retention ago
+func (s *Server) pruneLocked() {
+	expired := time.Now().Add(-s.config.Retention)
+	for id, j := range s.jobs {
+		if j.finishedBefore(expired) {
+			delete(s.jobs, id)
+			s.deleteRecord(id)
+		}
+	}
+}
+
+// deleteRecord removes the record of an execution from the store, if any
+func (s *Server) deleteRecord(id string) {
+	if s.config.Store == nil {
+		return
+	}
+	if err := s.config.Store.Delete(id); err != nil {
+		s.config.ErrorLog.Printf("gozero: could not delete execution %s: %v", id, err)
+	}
+}
+
+func (s *Server) job(id string) *job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.jobs[id]
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req ExecutionRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	j, err := s.submit(&req)
+	var badReq *badRequestError
+	switch {
+	case errors.As(err, &badReq):
+		writeError(w, http.StatusBadRequest, err)
+		return
+	case errors.Is(err, ErrQueueFull), errors.Is(err, ErrServerClosed):
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if req.Mode != ModeSync {
+		w.Header().Set("Location", "/v1/executions/"+j.record.Execution.ID)
+		writeJSON(w, http.StatusAccepted, j.snapshot())
+		return
+	}
+	select {
+	case <-j.done:
+	case <-r.Context().Done():
+		// the client went away, nobody is waiting for the result
+		j.cancelJob()
+		<-j.done
+	}
+	writeJSON(w, http.StatusOK, j.snapshot())
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	j := s.job(r.PathValue("id"))
+	if j == nil {
+		writeError(w, http.StatusNotFound, errors.New("execution not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, j.snapshot())
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	j := s.job(r.PathValue("id"))
+	if j == nil {
+		writeError(w, http.StatusNotFound, errors.New("execution not found"))
+		return
+	}
+	if !j.cancelJob() {
+		writeError(w, http.StatusConflict, errors.New("execution already finished"))
+		return
+	}
+	<-j.done
+	writeJSON(w, http.StatusOK, j.snapshot())
+}
+
+// handleEvents streams the output of an execution as server-sent events.
+// Output produced before the client connected is replayed first and the
+// stream ends with a done event carrying the final execution
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	j := s.job(r.PathValue("id"))
+	if j == nil {
+		writeError(w, http.StatusNotFound, errors.New("execution not found"))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sent := 0
+	for {
+		events, more := j.eventsFrom(sent)
+		for _, e := range events {
+			var data []byte
+			var err error
+			if e.typ == EventDone {
+				data, err = json.Marshal(j.snapshot())
+			} else {
+				data, err = json.Marshal(string(e.data))
+			}
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.typ, data); err != nil {
+				return
+			}
+			if e.typ == EventDone {
+				flusher.Flush()
+				return
+			}
+		}
+		sent += len(events)
+		flusher.Flush()
+		select {
+		case <-more:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// badRequestError marks errors caused by an invalid request
+type badRequestError struct {
+	err error
+}
+
+func badRequest(err error) error {
+	return &badRequestError{err: err}
+}
+
+func (e *badRequestError) Error() string {
+	return e.err.Error()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func validBackend(backend string) bool {
+	for _, b := range runner.Backends {
+		if backend == b {
+			return true
+		}
+	}
+	return false
+}
+
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// mergeLimits returns the profile limits tightened by the request limits.
+// Requests loosening a limit of the profile are rejected
+func mergeLimits(profile Profile, request Limits) (Limits, error) {
+	limits := profile.Limits
+	if request.Image != "" && request.Image != limits.Image {
+		if !profile.AllowImage {
+			return limits, errors.New("the image cannot be changed")
+		}
+		limits.Image = request.Image
+	}
+	if request.Network != "" && request.Network != limits.Network {
+		if request.Network != "none" && !profile.AllowNetwork {
+			return limits, errors.New("the network cannot be changed")
+		}
+		limits.Network = request.Network
+	}
+	if request.Memory != "" {
+		memory, err := parseMemory(request.Memory)
+		if err != nil {
+			return limits, err
+		}
+		if limits.Memory != "" {
+			limit, err := parseMemory(limits.Memory)
+			if err != nil {
+				return limits, err
+			}
+			if memory > limit {
+				return limits, fmt.Errorf("memory limit %s exceeds %s", request.Memory, limits.Memory)
+			}
+		}
+		limits.Memory = request.Memory
+	}
+	if request.CPUs != "" {
+		cpus, err := strconv.ParseFloat(request.CPUs, 64)
+		if err != nil || cpus <= 0 {
+			return limits, fmt.Errorf("invalid cpu limit %q", request.CPUs)
+		}
+		if limits.CPUs != "" {
+			limit, err := strconv.ParseFloat(limits.CPUs, 64)
+			if err != nil {
+				return limits, fmt.Errorf("invalid cpu limit %q", limits.CPUs)
+			}
+			if cpus > limit {
+				return limits, fmt.Errorf("cpu limit %s exceeds %s", request.CPUs, limits.CPUs)
+			}
+		}
+		limits.CPUs = request.CPUs
+	}
+	if limits.Timeout == 0 || (request.Timeout > 0 && request.Timeout < limits.Timeout) {
+		limits.Timeout = request.Timeout
+	}
+	return limits, nil
+}
+
+// parseMemory parses a memory limit with an optional b, k, m or g unit (e.g. 512m)
+func parseMemory(memory string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(memory))
+	multiplier := int64(1)
+	for i, unit := range []string{"b", "k", "m", "g"} {
+		if strings.HasSuffix(value, unit) {
+			value = strings.TrimSuffix(value, unit)
+			multiplier = int64(1) << (10 * i)
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid memory limit %q", memory)
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,287 @@
+//go:build !windows
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/gozero/internal/runner"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "secret"
+
+// unsandboxedProfiles are the profiles of test servers, tests only run trusted sources
+var unsandboxedProfiles = map[string]Profile{DefaultProfile: {Backend: runner.BackendNone}}
+
+func newTestServer(t *testing.T, config Config) *httptest.Server {
+	t.Helper()
+	config.Tokens = []string{testToken}
+	if config.Profiles == nil {
+		config.Profiles = unsandboxedProfiles
+	}
+	s, err := New(config)
+	require.NoError(t, err)
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		ts.Close()
+		_ = s.Close()
+	})
+	return ts
+}
+
+func do(t *testing.T, method, url string, body any) (*http.Response, []byte) {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&payload).Encode(body))
+	}
+	req, err := http.NewRequest(method, url, &payload)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	var data bytes.Buffer
+	_, err = data.ReadFrom(resp.Body)
+	require.NoError(t, err)
+	return resp, data.Bytes()
+}
+
+type testExecution struct {
+	ID     string `json:"id"`
+	Status Status `json:"status"`
+	Error  string `json:"error"`
+	Result *struct {
+		ExitCode int    `json:"exit_code"`
+		Stdout   string `json:"stdout"`
+		Stderr   string `json:"stderr"`
+	} `json:"result"`
+}
+
+func submit(t *testing.T, ts *httptest.Server, req ExecutionRequest) (int, testExecution) {
+	t.Helper()
+	resp, data := do(t, http.MethodPost, ts.URL+"/v1/executions", req)
+	var execution testExecution
+	require.NoError(t, json.Unmarshal(data, &execution), string(data))
+	return resp.StatusCode, execution
+}
+
+func waitStatus(t *testing.T, ts *httptest.Server, id string, statuses ...Status) testExecution {
+	t.Helper()
+	var execution testExecution
+	require.Eventually(t, func() bool {
+		_, data := do(t, http.MethodGet, ts.URL+"/v1/executions/"+id, nil)
+		require.NoError(t, json.Unmarshal(data, &execution))
+		for _, status := range statuses {
+			if execution.Status == status {
+				return true
+			}
+		}
+		return false
+	}, 10*time.Second, 20*time.Millisecond)
+	return execution
+}
+
+func TestServerAuth(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	resp, err := http.Post(ts.URL+"/v1/executions", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/executions/unknown", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, _ = do(t, http.MethodGet, ts.URL+"/v1/executions/unknown", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServerSync(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	input := "from stdin"
+
+	status, execution := submit(t, ts, ExecutionRequest{
+		Source:    `echo "$NAME"; cat`,
+		Language:  "sh",
+		Input:     &input,
+		Variables: map[string]string{"NAME": "gozero"},
+		Mode:      ModeSync,
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, StatusSucceeded, execution.Status)
+	require.Equal(t, "gozero\nfrom stdin", execution.Result.Stdout)
+
+	status, execution = submit(t, ts, ExecutionRequest{Source: "echo oops >&2; exit 3", Language: "sh", Mode: ModeSync})
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, StatusFailed, execution.Status)
+	require.Equal(t, 3, execution.Result.ExitCode)
+	require.Equal(t, "oops\n", execution.Result.Stderr)
+	require.NotEmpty(t, execution.Error)
+}
+
+func TestServerAsync(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	status, execution := submit(t, ts, ExecutionRequest{Source: "echo async", Language: "sh"})
+	require.Equal(t, http.StatusAccepted, status)
+	require.NotEmpty(t, execution.ID)
+
+	execution = waitStatus(t, ts, execution.ID, StatusSucceeded)
+	require.Equal(t, "async\n", execution.Result.Stdout)
+}
+
+func TestServerTimeout(t *testing.T) {
+	ts := newTestServer(t, Config{MaxTimeout: 100 * time.Millisecond})
+
+	_, execution := submit(t, ts, ExecutionRequest{Source: "exec sleep 10", Language: "sh", Mode: ModeSync})
+	require.Equal(t, StatusFailed, execution.Status)
+	require.Contains(t, execution.Error, "timed out")
+}
+
+func TestServerCancel(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	_, execution := submit(t, ts, ExecutionRequest{Source: "exec sleep 10", Language: "sh"})
+	waitStatus(t, ts, execution.ID, StatusRunning)
+
+	resp, data := do(t, http.MethodPost, ts.URL+"/v1/executions/"+execution.ID+"/cancel", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.Unmarshal(data, &execution))
+	require.Equal(t, StatusCanceled, execution.Status)
+
+	resp, _ = do(t, http.MethodPost, ts.URL+"/v1/executions/"+execution.ID+"/cancel", nil)
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestServerEvents(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	_, execution := submit(t, ts, ExecutionRequest{Source: "echo one; sleep 0.2; echo two >&2", Language: "sh"})
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/executions/"+execution.ID+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var events []string
+	var last string
+	scanner := bufio.NewScanner(resp.Body)
+	var typ string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := strings.TrimPrefix(line, "data: ")
+			if typ == EventDone {
+				last = data
+				continue
+			}
+			var chunk string
+			require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+			events = append(events, typ+":"+chunk)
+		}
+	}
+	require.Equal(t, []string{"stdout:one\n", "stderr:two\n"}, events)
+	require.NoError(t, json.Unmarshal([]byte(last), &execution))
+	require.Equal(t, StatusSucceeded, execution.Status)
+}
+
+func TestServerQueue(t *testing.T) {
+	ts := newTestServer(t, Config{MaxConcurrency: 1, QueueSize: 1})
+
+	_, running := submit(t, ts, ExecutionRequest{Source: "exec sleep 10", Language: "sh"})
+	waitStatus(t, ts, running.ID, StatusRunning)
+	status, queued := submit(t, ts, ExecutionRequest{Source: "echo queued", Language: "sh"})
+	require.Equal(t, http.StatusAccepted, status)
+	require.Equal(t, StatusQueued, queued.Status)
+
+	resp, _ := do(t, http.MethodPost, ts.URL+"/v1/executions", ExecutionRequest{Source: "echo full", Language: "sh"})
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	// the queued execution runs once the running one is canceled
+	resp, _ = do(t, http.MethodPost, ts.URL+"/v1/executions/"+running.ID+"/cancel", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	queued = waitStatus(t, ts, queued.ID, StatusSucceeded)
+	require.Equal(t, "queued\n", queued.Result.Stdout)
+}
+
+func TestServerDefaultProfile(t *testing.T) {
+	// executions are never run unsandboxed implicitly
+	_, err := New(Config{})
+	require.ErrorIs(t, err, ErrNoDefaultProfile)
+	_, err = New(Config{Profiles: map[string]Profile{"docker": {Backend: runner.BackendDocker}}})
+	require.ErrorIs(t, err, ErrNoDefaultProfile)
+}
+
+func TestServerBadRequest(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	for _, req := range []ExecutionRequest{
+		{Source: "echo", Language: "cobol"},
+		{Source: "echo"},
+		{Source: "echo", Language: "sh", Profile: "unknown"},
+		{Source: "echo", Language: "sh", Limits: Limits{Memory: "1g"}},
+		{Source: "echo", Language: "sh", Mode: "later"},
+	} {
+		resp, data := do(t, http.MethodPost, ts.URL+"/v1/executions", req)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, string(data))
+	}
+}
+
+func TestMergeLimits(t *testing.T) {
+	profile := Profile{
+		Backend: runner.BackendDocker,
+		Limits:  Limits{Image: "python:3-slim", Memory: "512m", CPUs: "1", Timeout: Duration(time.Minute)},
+	}
+	limits, err := mergeLimits(profile, Limits{Memory: "256M", CPUs: "0.5", Network: "none", Timeout: Duration(time.Second)})
+	require.NoError(t, err)
+	require.Equal(t, Limits{Image: "python:3-slim", Memory: "256M", CPUs: "0.5", Network: "none", Timeout: Duration(time.Second)}, limits)
+
+	// requests cannot loosen the profile
+	for _, request := range []Limits{
+		{Image: "attacker/image"},
+		{Network: "host"},
+		{Memory: "1g"},
+		{Memory: "lots"},
+		{CPUs: "2"},
+	} {
+		_, err := mergeLimits(profile, request)
+		require.Error(t, err, "%+v", request)
+	}
+
+	// unless the profile allows it
+	profile.AllowImage, profile.AllowNetwork = true, true
+	limits, err = mergeLimits(profile, Limits{Image: "node:slim", Network: "bridge"})
+	require.NoError(t, err)
+	require.Equal(t, "node:slim", limits.Image)
+	require.Equal(t, "bridge", limits.Network)
+
+	// limits left unset by the profile can be set
+	limits, err = mergeLimits(Profile{Backend: runner.BackendDocker}, Limits{Memory: "1g", CPUs: "2"})
+	require.NoError(t, err)
+	require.Equal(t, "1g", limits.Memory)
+	require.Equal(t, "2", limits.CPUs)
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// RecoveryPolicy decides what happens to executions which were running
+// when the server stopped
+type RecoveryPolicy uint8
+
+const (
+	// RecoverRequeue queues interrupted executions again (up to Config.MaxAttempts runs)
+	RecoverRequeue RecoveryPolicy = iota
+	// RecoverFail marks interrupted executions as failed
+	RecoverFail
+)
+
+// ErrInterrupted is the error of executions interrupted by a server restart
+var ErrInterrupted = errors.New("execution interrupted by server restart")
+
+// Record is the persisted state of an execution
+type Record struct {
+	// Sequence orders records by submission (assigned by the store)
+	Sequence  uint64           `json:"sequence"`
+	Request   ExecutionRequest `json:"request"`
+	Execution Execution        `json:"execution"`
+	// Attempts is the number of times the execution was started
+	Attempts int `json:"attempts"`
+}
+
+// Store persists executions so that queued and running executions
+// survive a restart. Implementations must be safe for concurrent use
+type Store interface {
+	// Save creates or replaces the record of an execution
+	Save(record *Record) error
+	// Load returns all records in submission order
+	Load() ([]*Record, error)
+	// Delete removes the record of an execution
+	Delete(id string) error
+	// Close releases the store
+	Close() error
+}
+
+var executionsBucket = []byte("executions")
+
+// BoltStore is a Store backed by a bbolt database file
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (or creates) the bbolt database at path
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(executionsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Save creates or replaces the record of an execution
+func (b *BoltStore) Save(record *Record) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(executionsBucket)
+		if record.Sequence == 0 {
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			record.Sequence = seq
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(record.Execution.ID), data)
+	})
+}
+
+// Load returns all records in submission order
+func (b *BoltStore) Load() ([]*Record, error) {
+	var records []*Record
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(executionsBucket).ForEach(func(k, v []byte) error {
+			record := &Record{}
+			if err := json.Unmarshal(v, record); err != nil {
+				return fmt.Errorf("could not decode execution %s: %w", k, err)
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Sequence < records[j].Sequence
+	})
+	return records, err
+}
+
+// Delete removes the record of an execution
+func (b *BoltStore) Delete(id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(executionsBucket).Delete([]byte(id))
+	})
+}
+
+// Close closes the database
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
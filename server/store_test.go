@@ -0,0 +1,119 @@
+//go:build !windows
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// restartableServer runs servers over the same bbolt store
+type restartableServer struct {
+	t      *testing.T
+	path   string
+	store  *BoltStore
+	server *Server
+	ts     *httptest.Server
+}
+
+func (r *restartableServer) start(config Config) *httptest.Server {
+	r.t.Helper()
+	store, err := NewBoltStore(r.path)
+	require.NoError(r.t, err)
+	config.Tokens = []string{testToken}
+	config.Store = store
+	if config.Profiles == nil {
+		config.Profiles = unsandboxedProfiles
+	}
+	s, err := New(config)
+	require.NoError(r.t, err)
+	r.store, r.server, r.ts = store, s, httptest.NewServer(s)
+	return r.ts
+}
+
+func (r *restartableServer) stop() {
+	r.ts.Close()
+	require.NoError(r.t, r.server.Close())
+	require.NoError(r.t, r.store.Close())
+}
+
+func newRestartableServer(t *testing.T) *restartableServer {
+	r := &restartableServer{t: t, path: filepath.Join(t.TempDir(), "gozero.db")}
+	t.Cleanup(func() {
+		if r.ts != nil {
+			r.stop()
+		}
+	})
+	return r
+}
+
+// interruptedSource sleeps on its first run and succeeds once restarted
+const interruptedSource = `if [ -f "$MARKER" ]; then echo recovered; else touch "$MARKER"; exec sleep 10; fi`
+
+func TestRecoveryRequeue(t *testing.T) {
+	r := newRestartableServer(t)
+	ts := r.start(Config{MaxConcurrency: 1})
+
+	marker := filepath.Join(t.TempDir(), "marker")
+	_, running := submit(t, ts, ExecutionRequest{Source: interruptedSource, Language: "sh", Variables: map[string]string{"MARKER": marker}})
+	waitStatus(t, ts, running.ID, StatusRunning)
+	_, queued := submit(t, ts, ExecutionRequest{Source: "echo queued", Language: "sh"})
+	_, finished := submit(t, ts, ExecutionRequest{Source: "echo done", Language: "sh", Profile: DefaultProfile})
+	r.stop()
+
+	ts = r.start(Config{MaxConcurrency: 1})
+	running = waitStatus(t, ts, running.ID, StatusSucceeded)
+	require.Equal(t, "recovered\n", running.Result.Stdout)
+	queued = waitStatus(t, ts, queued.ID, StatusSucceeded)
+	require.Equal(t, "queued\n", queued.Result.Stdout)
+	waitStatus(t, ts, finished.ID, StatusSucceeded)
+
+	records, err := r.store.Load()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, running.ID, records[0].Execution.ID)
+	require.Equal(t, 2, records[0].Attempts)
+}
+
+func TestRecoveryFail(t *testing.T) {
+	r := newRestartableServer(t)
+	ts := r.start(Config{})
+
+	_, running := submit(t, ts, ExecutionRequest{Source: "exec sleep 10", Language: "sh"})
+	waitStatus(t, ts, running.ID, StatusRunning)
+	r.stop()
+
+	ts = r.start(Config{Recovery: RecoverFail})
+	resp, data := do(t, http.MethodGet, ts.URL+"/v1/executions/"+running.ID, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, string(data), ErrInterrupted.Error())
+	require.Contains(t, string(data), `"status":"failed"`)
+}
+
+func TestRecoveryExpire(t *testing.T) {
+	r := newRestartableServer(t)
+	ts := r.start(Config{})
+
+	status, execution := submit(t, ts, ExecutionRequest{Source: "echo persisted", Language: "sh", Mode: ModeSync})
+	require.Equal(t, http.StatusOK, status)
+	r.stop()
+
+	// finished results survive a restart
+	ts = r.start(Config{})
+	execution = waitStatus(t, ts, execution.ID, StatusSucceeded)
+	require.Equal(t, "persisted\n", execution.Result.Stdout)
+	r.stop()
+
+	time.Sleep(10 * time.Millisecond)
+	ts = r.start(Config{Retention: time.Millisecond})
+	resp, _ := do(t, http.MethodGet, ts.URL+"/v1/executions/"+execution.ID, nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	records, err := r.store.Load()
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
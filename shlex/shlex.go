@@ -0,0 +1,106 @@
+// shlex package splits command lines into arguments following the POSIX
+// shell quoting rules, without running a shell. Quotes and backslashes are
+// removed, nothing is expanded (variables, globs, ~) and operators such as
+// | ; > and # have no special meaning.
+package shlex
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrUnterminatedQuote is returned when a single or double quote is not closed
+	ErrUnterminatedQuote = errors.New("unterminated quote")
+	// ErrTrailingEscape is returned when the line ends with a backslash
+	ErrTrailingEscape = errors.New("trailing escape character")
+)
+
+// Split splits line into arguments:
+//
+//   - unquoted blanks (space, tab and newline) separate arguments
+//   - a backslash outside quotes preserves the next character, backslash-newline is removed
+//   - characters between single quotes are preserved as is
+//   - between double quotes, a backslash only escapes $ ` " \ and newline
+//   - empty single or double quotes are an empty argument
+//
+// Split returns ErrUnterminatedQuote or ErrTrailingEscape (with the offset
+// of the offending character) on invalid lines
+func Split(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		// inArg is set once the current argument started, quotes start an argument even when empty
+		inArg bool
+	)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch c {
+		case ' ', '\t', '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case '\\':
+			if i+1 >= len(line) {
+				return nil, fmt.Errorf("%w at offset %d", ErrTrailingEscape, i)
+			}
+			i++
+			if line[i] != '\n' {
+				current.WriteByte(line[i])
+				inArg = true
+			}
+		case '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("%w at offset %d", ErrUnterminatedQuote, i)
+			}
+			current.WriteString(line[i+1 : i+1+end])
+			inArg = true
+			i += end + 1
+		case '"':
+			end, err := doubleQuoted(line, i, &current)
+			if err != nil {
+				return nil, err
+			}
+			inArg = true
+			i = end
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// doubleQuoted writes the content of the double quoted string starting at
+// offset start to current and returns the offset of the closing quote
+func doubleQuoted(line string, start int, current *strings.Builder) (int, error) {
+	for i := start + 1; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '"':
+			return i, nil
+		case '\\':
+			if i+1 < len(line) {
+				switch next := line[i+1]; next {
+				case '$', '`', '"', '\\':
+					current.WriteByte(next)
+					i++
+					continue
+				case '\n':
+					i++
+					continue
+				}
+			}
+			current.WriteByte(c)
+		default:
+			current.WriteByte(c)
+		}
+	}
+	return 0, fmt.Errorf("%w at offset %d", ErrUnterminatedQuote, start)
+}
@@ -0,0 +1,48 @@
+package shlex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"  \t\n ", nil},
+		{"python3 -c 'print(1 + 2)'", []string{"python3", "-c", "print(1 + 2)"}},
+		{`echo  "a  b"   c`, []string{"echo", "a  b", "c"}},
+		{`echo '' ""`, []string{"echo", "", ""}},
+		{`a'b'"c"d`, []string{"abcd"}},
+		{`echo a\ b \'c\' \\`, []string{"echo", "a b", "'c'", `\`}},
+		{"echo a\\\nb", []string{"echo", "ab"}},
+		{`echo "\$HOME \"q\" \\ \n \a"`, []string{"echo", `$HOME "q" \ \n \a`}},
+		{"echo \"a\\\nb\"", []string{"echo", "ab"}},
+		{`echo 'a\"b' "it's"`, []string{"echo", `a\"b`, "it's"}},
+		{`echo $HOME ~ * | grep # x`, []string{"echo", "$HOME", "~", "*", "|", "grep", "#", "x"}},
+		{"sh -c 'echo \"$1\"' -- 'héllo wörld'", []string{"sh", "-c", `echo "$1"`, "--", "héllo wörld"}},
+	}
+	for _, tt := range tests {
+		got, err := Split(tt.line)
+		require.Nil(t, err, tt.line)
+		require.Equal(t, tt.want, got, tt.line)
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	for line, want := range map[string]error{
+		`echo 'a`:    ErrUnterminatedQuote,
+		`echo "a`:    ErrUnterminatedQuote,
+		`echo "a\"`:  ErrUnterminatedQuote,
+		`echo 'a'"'`: ErrUnterminatedQuote,
+		`echo a\`:    ErrTrailingEscape,
+	} {
+		_, err := Split(line)
+		require.ErrorIs(t, err, want, line)
+	}
+	_, err := Split(`echo "a`)
+	require.EqualError(t, err, "unterminated quote at offset 5")
+}
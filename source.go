@@ -19,6 +19,10 @@ type Source struct {
 	CloseAfterWrite bool
 	Filename        string
 	File            *os.File
+	// Metadata describes the source (e.g. origin, trust) for execution policies
+	Metadata map[string]string
+	// Language is a hint of the source language (e.g. python) used by MultiEngine
+	Language string
 }
 
 func NewSource() (*Source, error) {
@@ -68,7 +72,10 @@ func NewSourceWithReader(src io.Reader, wantedPattern, dir string) (*Source, err
 
 func (s *Source) Close() error {
 	if s.File != nil {
-		return s.File.Close()
+		// the file may already be closed (e.g. EarlyCloseFileDescriptor)
+		if err := s.File.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,104 @@
+package types
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/saintfish/chardet"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+)
+
+// EncodingUTF8 is the encoding reported for output which is already valid UTF-8
+const EncodingUTF8 = "UTF-8"
+
+// NormalizeCharset detects the charset of stdout and stderr and transcodes
+// them to UTF-8. The detected encodings are recorded in StdoutEncoding and
+// StderrEncoding and the original bytes remain available via RawStdout and RawStderr.
+// Output which cannot be transcoded is left untouched
+func (r *Result) NormalizeCharset() error {
+	var err error
+	r.StdoutEncoding, r.rawStdout, err = normalizeBuffer(&r.Stdout)
+	if err != nil {
+		return err
+	}
+	r.StderrEncoding, r.rawStderr, err = normalizeBuffer(&r.Stderr)
+	return err
+}
+
+// RawStdout returns stdout as produced by the command (before charset normalization)
+func (r *Result) RawStdout() []byte {
+	if r.rawStdout != nil {
+		return r.rawStdout
+	}
+	return r.Stdout.Bytes()
+}
+
+// RawStderr returns stderr as produced by the command (before charset normalization)
+func (r *Result) RawStderr() []byte {
+	if r.rawStderr != nil {
+		return r.rawStderr
+	}
+	return r.Stderr.Bytes()
+}
+
+// DetectCharset returns the IANA name of the charset of data.
+// Valid UTF-8 without NUL bytes (which hint at UTF-16/32) is reported as UTF-8
+func DetectCharset(data []byte) string {
+	if utf8.Valid(data) && bytes.IndexByte(data, 0) < 0 {
+		return EncodingUTF8
+	}
+	result, err := chardet.NewTextDetector().DetectBest(data)
+	if err != nil {
+		return ""
+	}
+	return result.Charset
+}
+
+// normalizeBuffer transcodes buf to UTF-8 in place and returns the detected
+// charset and the raw bytes (nil when buf was not modified)
+func normalizeBuffer(buf *bytes.Buffer) (string, []byte, error) {
+	if buf.Len() == 0 {
+		return "", nil, nil
+	}
+	charset := DetectCharset(buf.Bytes())
+	if charset == "" || strings.EqualFold(charset, EncodingUTF8) {
+		return charset, nil, nil
+	}
+	enc := encodingFor(charset)
+	if enc == nil {
+		return charset, nil, nil
+	}
+	decoded, err := enc.NewDecoder().Bytes(buf.Bytes())
+	if err != nil {
+		return charset, nil, err
+	}
+	raw := bytes.Clone(buf.Bytes())
+	buf.Reset()
+	buf.Write(decoded)
+	return charset, raw, nil
+}
+
+// encodingFor maps a charset name reported by chardet to its encoding
+func encodingFor(charset string) encoding.Encoding {
+	switch strings.ToUpper(charset) {
+	case "UTF-16LE":
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case "UTF-16BE":
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	case "UTF-32LE":
+		return utf32.UTF32(utf32.LittleEndian, utf32.UseBOM)
+	case "UTF-32BE":
+		return utf32.UTF32(utf32.BigEndian, utf32.UseBOM)
+	case "GB-18030":
+		charset = "GB18030"
+	}
+	enc, err := ianaindex.IANA.Encoding(charset)
+	if err != nil {
+		return nil
+	}
+	return enc
+}
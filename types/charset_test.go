@@ -0,0 +1,45 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestNormalizeCharset(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding encoding.Encoding
+		text     string
+		charset  string
+	}{
+		{"latin1", charmap.ISO8859_1, strings.Repeat("Le café était très animé à côté de l'hôtel. ", 4), "ISO-8859-1"},
+		{"utf16", unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), "hello from a windows console\n", "UTF-16LE"},
+		{"shiftjis", japanese.ShiftJIS, strings.Repeat("これは日本語のテキストです。文字コードを検出します。", 3), "Shift_JIS"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := tc.encoding.NewEncoder().Bytes([]byte(tc.text))
+			require.Nil(t, err)
+
+			res := &Result{}
+			res.Stdout.Write(raw)
+			require.Nil(t, res.NormalizeCharset())
+			require.Equal(t, tc.charset, res.StdoutEncoding)
+			require.Equal(t, tc.text, res.Stdout.String())
+			require.Equal(t, raw, res.RawStdout())
+		})
+	}
+
+	res := &Result{}
+	res.Stdout.WriteString("plain utf-8 ✓")
+	require.Nil(t, res.NormalizeCharset())
+	require.Equal(t, EncodingUTF8, res.StdoutEncoding)
+	require.Equal(t, "plain utf-8 ✓", string(res.RawStdout()))
+	require.Empty(t, res.StderrEncoding)
+}
@@ -0,0 +1,383 @@
+package types
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats reported in DecodeError
+const (
+	FormatJSON      = "json"
+	FormatJSONLines = "jsonl"
+	FormatYAML      = "yaml"
+	FormatCSV       = "csv"
+	FormatKeyValue  = "kv"
+)
+
+// DecodeError is returned when the output of a result cannot be decoded.
+// Line and Column are 1-based and zero when unknown, Offset is the byte
+// offset in the output (-1 when unknown)
+type DecodeError struct {
+	Format string
+	Line   int
+	Column int
+	Offset int64
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Format)
+	if e.Line > 0 {
+		fmt.Fprintf(&sb, ": line %d", e.Line)
+		if e.Column > 0 {
+			fmt.Fprintf(&sb, ", column %d", e.Column)
+		}
+	} else if e.Offset >= 0 {
+		fmt.Fprintf(&sb, ": offset %d", e.Offset)
+	}
+	sb.WriteString(": ")
+	sb.WriteString(e.Err.Error())
+	return sb.String()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeJSON decodes stdout as a single JSON value into v
+func (r *Result) DecodeJSON(v any) error {
+	return decodeJSON(r.Stdout.Bytes(), v)
+}
+
+// DecodeJSONLines decodes stdout as JSON Lines into v which must be a pointer to a slice.
+// Empty lines are skipped
+func (r *Result) DecodeJSONLines(v any) error {
+	slice, err := slicePointer(v)
+	if err != nil {
+		return err
+	}
+	lineNum := 0
+	offset := int64(0)
+	err = forEachLine(r.Stdout.Bytes(), func(line []byte) error {
+		lineNum++
+		lineOffset := offset
+		offset += int64(len(line)) + 1
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		}
+		elem := reflect.New(slice.Type().Elem())
+		if err := json.Unmarshal(line, elem.Interface()); err != nil {
+			decodeErr := &DecodeError{Format: FormatJSONLines, Line: lineNum, Offset: lineOffset, Err: err}
+			if col, ok := jsonErrorOffset(err); ok {
+				decodeErr.Column = int(col)
+				decodeErr.Offset = lineOffset + col - 1
+			}
+			return decodeErr
+		}
+		slice.Set(reflect.Append(slice, elem.Elem()))
+		return nil
+	})
+	return err
+}
+
+// DecodeYAML decodes stdout as YAML into v
+func (r *Result) DecodeYAML(v any) error {
+	if err := yaml.Unmarshal(r.Stdout.Bytes(), v); err != nil {
+		return &DecodeError{Format: FormatYAML, Line: yamlErrorLine(err), Offset: -1, Err: err}
+	}
+	return nil
+}
+
+// DecodeCSV decodes stdout as CSV with a header row into v, which must be a pointer to
+// a slice of structs (fields matched by `csv` tag or name), []map[string]string or [][]string.
+// For [][]string the header row is included
+func (r *Result) DecodeCSV(v any) error {
+	slice, err := slicePointer(v)
+	if err != nil {
+		return err
+	}
+	reader := csv.NewReader(bytes.NewReader(r.Stdout.Bytes()))
+	records, err := reader.ReadAll()
+	if err != nil {
+		decodeErr := &DecodeError{Format: FormatCSV, Offset: -1, Err: err}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			decodeErr.Line, decodeErr.Column = parseErr.Line, parseErr.Column
+		}
+		return decodeErr
+	}
+
+	elemType := slice.Type().Elem()
+	if elemType == reflect.TypeOf([]string(nil)) {
+		slice.Set(reflect.ValueOf(records))
+		return nil
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	header := records[0]
+	for i, record := range records[1:] {
+		line := i + 2
+		elem := reflect.New(elemType).Elem()
+		switch {
+		case elemType == reflect.TypeOf(map[string]string(nil)):
+			row := make(map[string]string, len(header))
+			for col, name := range header {
+				if col < len(record) {
+					row[name] = record[col]
+				}
+			}
+			elem.Set(reflect.ValueOf(row))
+		case elemType.Kind() == reflect.Struct:
+			for col, name := range header {
+				if col >= len(record) {
+					break
+				}
+				if err := setField(elem, "csv", name, record[col]); err != nil {
+					return &DecodeError{Format: FormatCSV, Line: line, Column: col + 1, Offset: -1, Err: err}
+				}
+			}
+		default:
+			return fmt.Errorf("unsupported csv element type %s", elemType)
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+	return nil
+}
+
+// DecodeKeyValue decodes stdout as key=value lines into v, which must be a pointer to
+// map[string]string or to a struct (fields matched by `kv` tag or name).
+// Empty lines and lines starting with # are skipped
+func (r *Result) DecodeKeyValue(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("decode target must be a non-nil pointer")
+	}
+	target := rv.Elem()
+	isMap := target.Type() == reflect.TypeOf(map[string]string(nil))
+	if !isMap && target.Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported key=value target %s", target.Type())
+	}
+	if isMap && target.IsNil() {
+		target.Set(reflect.ValueOf(map[string]string{}))
+	}
+
+	lineNum := 0
+	offset := int64(0)
+	return forEachLine(r.Stdout.Bytes(), func(raw []byte) error {
+		lineNum++
+		lineOffset := offset
+		offset += int64(len(raw)) + 1
+		line := strings.TrimSpace(string(raw))
+		if line == "" || strings.HasPrefix(line, "#") {
+			return nil
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return &DecodeError{Format: FormatKeyValue, Line: lineNum, Offset: lineOffset, Err: errors.New("missing '=' separator")}
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if isMap {
+			target.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(value))
+			return nil
+		}
+		if err := setField(target, "kv", key, value); err != nil {
+			return &DecodeError{Format: FormatKeyValue, Line: lineNum, Column: len(key) + 2, Offset: lineOffset, Err: err}
+		}
+		return nil
+	})
+}
+
+// JSON decodes stdout of the result as a JSON value of type T
+func JSON[T any](r *Result) (T, error) {
+	var v T
+	err := r.DecodeJSON(&v)
+	return v, err
+}
+
+// JSONLines decodes stdout of the result as JSON Lines of type T
+func JSONLines[T any](r *Result) ([]T, error) {
+	var v []T
+	err := r.DecodeJSONLines(&v)
+	return v, err
+}
+
+// YAML decodes stdout of the result as a YAML value of type T
+func YAML[T any](r *Result) (T, error) {
+	var v T
+	err := r.DecodeYAML(&v)
+	return v, err
+}
+
+// CSV decodes stdout of the result as CSV rows of type T
+func CSV[T any](r *Result) ([]T, error) {
+	var v []T
+	err := r.DecodeCSV(&v)
+	return v, err
+}
+
+// KeyValue decodes stdout of the result as key=value lines into a T
+func KeyValue[T any](r *Result) (T, error) {
+	var v T
+	err := r.DecodeKeyValue(&v)
+	return v, err
+}
+
+func decodeJSON(data []byte, v any) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &DecodeError{Format: FormatJSON, Offset: -1, Err: errors.New("empty output")}
+	}
+	err := json.Unmarshal(data, v)
+	if err == nil {
+		return nil
+	}
+	decodeErr := &DecodeError{Format: FormatJSON, Offset: -1, Err: err}
+	if offset, ok := jsonErrorOffset(err); ok {
+		decodeErr.Offset = offset
+		decodeErr.Line, decodeErr.Column = lineColumn(data, offset)
+	}
+	return decodeErr
+}
+
+// jsonErrorOffset returns the byte offset reported by json errors
+func jsonErrorOffset(err error) (int64, bool) {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr.Offset, true
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return typeErr.Offset, true
+	}
+	return 0, false
+}
+
+// lineColumn converts the offset reported by encoding/json (number of
+// bytes read when the error was detected) into a 1-based line and column
+func lineColumn(data []byte, offset int64) (int, int) {
+	if len(data) == 0 || offset <= 0 {
+		return 0, 0
+	}
+	offset = min(max(offset, 1), int64(len(data)))
+	before := data[:offset-1]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - (bytes.LastIndexByte(before, '\n') + 1)
+	return line, column
+}
+
+var yamlLineRe = regexp.MustCompile(`line (\d+)`)
+
+// yamlErrorLine extracts the line number from yaml errors
+func yamlErrorLine(err error) int {
+	if match := yamlLineRe.FindStringSubmatch(err.Error()); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return line
+	}
+	return 0
+}
+
+// forEachLine calls fn for every line of data (without the line terminator)
+func forEachLine(data []byte, fn func(line []byte) error) error {
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(line, []byte("\n"))
+			if fnErr := fn(bytes.TrimSuffix(line, []byte("\r"))); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func slicePointer(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return reflect.Value{}, errors.New("decode target must be a non-nil pointer to a slice")
+	}
+	return rv.Elem(), nil
+}
+
+// setField sets the struct field matching name (by tag or case-insensitive field name)
+// from its string representation. Unknown names are ignored
+func setField(structValue reflect.Value, tag, name, value string) error {
+	structType := structValue.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldName, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if fieldName == "-" {
+			continue
+		}
+		if fieldName == "" {
+			if !strings.EqualFold(field.Name, name) {
+				continue
+			}
+		} else if fieldName != name {
+			continue
+		}
+		if err := setValue(structValue.Field(i), value); err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		return nil
+	}
+	return nil
+}
+
+func setValue(field reflect.Value, value string) error {
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
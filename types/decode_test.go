@@ -0,0 +1,101 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func resultWithStdout(stdout string) *Result {
+	res := &Result{}
+	res.Stdout.WriteString(stdout)
+	return res
+}
+
+func TestDecodeJSON(t *testing.T) {
+	type item struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	v, err := JSON[item](resultWithStdout(`{"name": "a", "count": 2}`))
+	require.Nil(t, err)
+	require.Equal(t, item{Name: "a", Count: 2}, v)
+
+	_, err = JSON[item](resultWithStdout("{\n  \"name\": \"a\",\n  \"count\": \"two\"\n}"))
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	require.Equal(t, 3, decodeErr.Line)
+
+	_, err = JSON[item](resultWithStdout("{\n  \"name\": \"a\",,\n}"))
+	require.ErrorAs(t, err, &decodeErr)
+	require.Equal(t, 2, decodeErr.Line)
+	require.Equal(t, 15, decodeErr.Column)
+
+	// scripts printing nothing are reported, not panicking
+	for _, stdout := range []string{"", " \n"} {
+		_, err = JSON[item](resultWithStdout(stdout))
+		require.ErrorAs(t, err, &decodeErr)
+		require.Equal(t, "json: empty output", decodeErr.Error())
+	}
+
+	items, err := JSONLines[item](resultWithStdout("{\"name\":\"a\"}\n\n{\"name\":\"b\",\"count\":1}\n"))
+	require.Nil(t, err)
+	require.Equal(t, []item{{Name: "a"}, {Name: "b", Count: 1}}, items)
+
+	_, err = JSONLines[item](resultWithStdout("{\"name\":\"a\"}\n{\"name\":}\n"))
+	require.ErrorAs(t, err, &decodeErr)
+	require.Equal(t, 2, decodeErr.Line)
+	require.Equal(t, int64(len("{\"name\":\"a\"}\n")+8), decodeErr.Offset)
+}
+
+func TestDecodeYAML(t *testing.T) {
+	v, err := YAML[map[string]int](resultWithStdout("a: 1\nb: 2\n"))
+	require.Nil(t, err)
+	require.Equal(t, map[string]int{"a": 1, "b": 2}, v)
+
+	_, err = YAML[map[string]int](resultWithStdout("a: 1\nb: [\n"))
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	require.NotZero(t, decodeErr.Line)
+}
+
+func TestDecodeCSV(t *testing.T) {
+	type row struct {
+		Host string `csv:"host"`
+		Port int    `csv:"port"`
+		Open bool
+	}
+	rows, err := CSV[row](resultWithStdout("host,port,open\nexample.com,443,true\nlocalhost,22,false\n"))
+	require.Nil(t, err)
+	require.Equal(t, []row{{"example.com", 443, true}, {"localhost", 22, false}}, rows)
+
+	maps, err := CSV[map[string]string](resultWithStdout("a,b\n1,2\n"))
+	require.Nil(t, err)
+	require.Equal(t, []map[string]string{{"a": "1", "b": "2"}}, maps)
+
+	_, err = CSV[row](resultWithStdout("host,port\nexample.com,https\n"))
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	require.Equal(t, 2, decodeErr.Line)
+	require.Equal(t, 2, decodeErr.Column)
+}
+
+func TestDecodeKeyValue(t *testing.T) {
+	type status struct {
+		Version string `kv:"VERSION"`
+		Workers int    `kv:"WORKERS"`
+	}
+	v, err := KeyValue[status](resultWithStdout("# comment\nVERSION=1.2.3\n\nWORKERS = 4\nIGNORED=x\n"))
+	require.Nil(t, err)
+	require.Equal(t, status{Version: "1.2.3", Workers: 4}, v)
+
+	m, err := KeyValue[map[string]string](resultWithStdout("a=1\nb=x=y\n"))
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"a": "1", "b": "x=y"}, m)
+
+	_, err = KeyValue[map[string]string](resultWithStdout("a=1\nbroken\n"))
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	require.Equal(t, 2, decodeErr.Line)
+	require.Equal(t, int64(4), decodeErr.Offset)
+}
@@ -0,0 +1,114 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+// Sentinel errors describing the outcome of an execution. They are returned
+// (possibly wrapped) by cmdexec and every sandbox backend and are meant to be
+// checked with errors.Is. Typed errors carrying details (exit code, signal)
+// can be extracted with errors.As.
+var (
+	// ErrTimeout is returned when the execution exceeded its deadline
+	ErrTimeout = errors.New("execution timed out")
+	// ErrKilled is returned when the execution was terminated by a signal (see KilledError)
+	ErrKilled = errors.New("execution killed by signal")
+	// ErrOOM is returned when the execution was killed for exceeding its memory limit
+	ErrOOM = errors.New("execution killed: out of memory")
+	// ErrNonZeroExit is returned when the execution exited with a non-zero code (see NonZeroExitError)
+	ErrNonZeroExit = errors.New("execution exited with non-zero code")
+	// ErrStartFailed is returned when the execution could not be started (e.g. missing interpreter)
+	ErrStartFailed = errors.New("execution failed to start")
+	// ErrBackendUnavailable is returned when a sandbox backend is not installed or not reachable
+	ErrBackendUnavailable = errors.New("sandbox backend unavailable")
+	// ErrPolicyViolation is returned when an execution is rejected by policy
+	ErrPolicyViolation = errors.New("execution policy violation")
+)
+
+// NonZeroExitError is returned when the execution exited with a non-zero code.
+// It matches ErrNonZeroExit with errors.Is
+type NonZeroExitError struct {
+	Code int
+}
+
+func (e *NonZeroExitError) Error() string {
+	return fmt.Sprintf("execution exited with code %d", e.Code)
+}
+
+// Is reports whether target is ErrNonZeroExit
+func (e *NonZeroExitError) Is(target error) bool {
+	return target == ErrNonZeroExit
+}
+
+// KilledError is returned when the execution was terminated by a signal.
+// It matches ErrKilled with errors.Is
+type KilledError struct {
+	Signal os.Signal
+}
+
+func (e *KilledError) Error() string {
+	if e.Signal == nil {
+		return ErrKilled.Error()
+	}
+	return fmt.Sprintf("execution killed by signal: %v", e.Signal)
+}
+
+// Is reports whether target is ErrKilled
+func (e *KilledError) Is(target error) bool {
+	return target == ErrKilled
+}
+
+// StartError marks err as a failure to start the execution
+func StartError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errkit.Append(ErrStartFailed, err)
+}
+
+// BackendUnavailableError marks err as the sandbox backend being unavailable
+func BackendUnavailableError(err error) error {
+	if err == nil {
+		return ErrBackendUnavailable
+	}
+	return errkit.Append(ErrBackendUnavailable, err)
+}
+
+// WaitError classifies the error returned while waiting for a process
+// started with ctx. It returns nil when err is nil
+func WaitError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errkit.Append(ErrTimeout, ctx.Err(), err)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	if sig, ok := signalOf(exitErr.ProcessState); ok {
+		return errkit.Append(&KilledError{Signal: sig}, err)
+	}
+	return errkit.Append(&NonZeroExitError{Code: exitErr.ExitCode()}, err)
+}
+
+// ExitCodeError classifies an exit code reported by a backend which does not
+// expose a process state (e.g. containers). Exit codes above 128 follow the
+// shell convention of 128+signal
+func ExitCodeError(code int) error {
+	switch {
+	case code == 0:
+		return nil
+	case code > 128 && code < 128+65:
+		return &KilledError{Signal: signalFromNumber(code - 128)}
+	default:
+		return &NonZeroExitError{Code: code}
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package types
+
+import (
+	"os"
+	"syscall"
+)
+
+// signalOf returns the signal which terminated the process if any
+func signalOf(state *os.ProcessState) (os.Signal, bool) {
+	if state == nil {
+		return nil, false
+	}
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return nil, false
+	}
+	return status.Signal(), true
+}
+
+func signalFromNumber(n int) os.Signal {
+	return syscall.Signal(n)
+}
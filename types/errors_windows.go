@@ -0,0 +1,18 @@
+//go:build windows
+
+package types
+
+import (
+	"os"
+	"syscall"
+)
+
+// signalOf returns the signal which terminated the process if any
+// (processes are never terminated by signals on windows)
+func signalOf(state *os.ProcessState) (os.Signal, bool) {
+	return nil, false
+}
+
+func signalFromNumber(n int) os.Signal {
+	return syscall.Signal(n)
+}
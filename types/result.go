@@ -16,7 +16,17 @@ type Result struct {
 	Stdout    bytes.Buffer
 	Stderr    bytes.Buffer
 	exitErr   *exec.ExitError // return exit error this includes exit code , command sysusage and more
+	exitCode  int             // exit code reported by backends without a local process (e.g. docker)
 	DebugData *bytes.Buffer   // only available when debug mode is enabled
+	Attempts  []Attempt       // attempts made when a retry policy is set
+	// Transcript of stdout and stderr in the order they were read (only set when enabled)
+	Transcript *Transcript
+
+	// charset detected for stdout and stderr (only set when charset normalization is enabled)
+	StdoutEncoding string
+	StderrEncoding string
+	rawStdout      []byte
+	rawStderr      []byte
 }
 
 // GetExitError returns the exit error if any.
@@ -29,10 +39,16 @@ func (r *Result) SetExitError(err *exec.ExitError) {
 	r.exitErr = err
 }
 
+// SetExitCode sets the exit code for backends which do not
+// run a local process (internal use only).
+func (r *Result) SetExitCode(code int) {
+	r.exitCode = code
+}
+
 // GetExitCode returns the exit code of the command.
 func (r *Result) GetExitCode() int {
 	if r.exitErr == nil {
-		return 0
+		return r.exitCode
 	}
 	return r.exitErr.ExitCode()
 }
@@ -0,0 +1,49 @@
+package types
+
+import "encoding/json"
+
+// resultJSON is the JSON representation of a Result
+type resultJSON struct {
+	Command        string      `json:"command"`
+	ExitCode       int         `json:"exit_code"`
+	Stdout         string      `json:"stdout"`
+	Stderr         string      `json:"stderr"`
+	StdoutEncoding string      `json:"stdout_encoding,omitempty"`
+	StderrEncoding string      `json:"stderr_encoding,omitempty"`
+	Attempts       []Attempt   `json:"attempts,omitempty"`
+	Transcript     *Transcript `json:"transcript,omitempty"`
+}
+
+// MarshalJSON encodes the result with its output as strings
+func (r *Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(resultJSON{
+		Command:        r.Command,
+		ExitCode:       r.GetExitCode(),
+		Stdout:         r.Stdout.String(),
+		Stderr:         r.Stderr.String(),
+		StdoutEncoding: r.StdoutEncoding,
+		StderrEncoding: r.StderrEncoding,
+		Attempts:       r.Attempts,
+		Transcript:     r.Transcript,
+	})
+}
+
+// UnmarshalJSON decodes a result encoded with MarshalJSON.
+// The exit code is restored but the original exit error is not
+func (r *Result) UnmarshalJSON(data []byte) error {
+	var v resultJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = Result{
+		Command:        v.Command,
+		exitCode:       v.ExitCode,
+		StdoutEncoding: v.StdoutEncoding,
+		StderrEncoding: v.StderrEncoding,
+		Attempts:       v.Attempts,
+		Transcript:     v.Transcript,
+	}
+	r.Stdout.WriteString(v.Stdout)
+	r.Stderr.WriteString(v.Stderr)
+	return nil
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultJSON(t *testing.T) {
+	res := &Result{Command: "sh script.sh", Attempts: []Attempt{{Number: 1}}}
+	res.Stdout.WriteString("out")
+	res.Stderr.WriteString("err")
+	res.SetExitCode(2)
+
+	data, err := json.Marshal(res)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"exit_code":2`)
+
+	var decoded Result
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, "sh script.sh", decoded.Command)
+	require.Equal(t, "out", decoded.Stdout.String())
+	require.Equal(t, "err", decoded.Stderr.String())
+	require.Equal(t, 2, decoded.GetExitCode())
+	require.Len(t, decoded.Attempts, 1)
+}
@@ -0,0 +1,138 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os/exec"
+	"time"
+)
+
+// Default values used by RetryPolicy fields left empty
+const (
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff     = 5 * time.Second
+	DefaultRetryMultiplier     = 2.0
+)
+
+// Attempt records a single try of an execution
+type Attempt struct {
+	Number   int           `json:"number"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// RetryPolicy controls how failed executions are retried.
+//
+// Executions are assumed to be non-idempotent: once the script started running
+// (non-zero exit, timeout, kill, oom...) it is only retried when AllowNonIdempotent
+// is set. Failures before the script ran (ErrStartFailed, ErrBackendUnavailable)
+// are always safe to retry
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one (<= 1 disables retries)
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after each retry
+	Multiplier float64
+	// Jitter randomizes each delay by up to +/- Jitter (fraction between 0 and 1)
+	Jitter float64
+	// Retryable overrides the classification of retryable errors (defaults to IsTransient)
+	Retryable func(err error) bool `json:"-"`
+	// AllowNonIdempotent allows retrying executions which may have already run
+	AllowNonIdempotent bool
+}
+
+// IsTransient reports whether err is a failure which happened before the
+// script started running and may succeed if tried again
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, exec.ErrNotFound) {
+		// missing interpreters do not appear on retry
+		return false
+	}
+	return errors.Is(err, ErrStartFailed) || errors.Is(err, ErrBackendUnavailable)
+}
+
+// ShouldRetry reports whether an execution failed with err should be retried
+func (p *RetryPolicy) ShouldRetry(err error) bool {
+	if p == nil || err == nil {
+		return false
+	}
+	if errors.Is(err, ErrPolicyViolation) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	transient := IsTransient(err)
+	if !transient && !p.AllowNonIdempotent {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return transient || p.AllowNonIdempotent
+}
+
+// Backoff returns the delay to wait before the given retry (1 for the first retry)
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	initial, maxBackoff, multiplier := p.InitialBackoff, p.MaxBackoff, p.Multiplier
+	if initial <= 0 {
+		initial = DefaultRetryInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoff
+	}
+	if multiplier < 1 {
+		multiplier = DefaultRetryMultiplier
+	}
+	delay := float64(initial)
+	for i := 1; i < retry && delay < float64(maxBackoff); i++ {
+		delay *= multiplier
+	}
+	delay = min(delay, float64(maxBackoff))
+	if p.Jitter > 0 {
+		jitter := min(p.Jitter, 1)
+		delay += delay * jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// Retry runs fn until it succeeds, the error is not retryable or the policy
+// attempts are exhausted. Every attempt is recorded on the returned result.
+// A nil policy runs fn exactly once
+func Retry(ctx context.Context, policy *RetryPolicy, fn func(ctx context.Context, attempt int) (*Result, error)) (*Result, error) {
+	maxAttempts := 1
+	if policy != nil && policy.MaxAttempts > 1 {
+		maxAttempts = policy.MaxAttempts
+	}
+
+	var attempts []Attempt
+	for number := 1; ; number++ {
+		started := time.Now()
+		res, err := fn(ctx, number)
+		attempt := Attempt{Number: number, Started: started, Duration: time.Since(started)}
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+		attempts = append(attempts, attempt)
+
+		if err == nil || number >= maxAttempts || !policy.ShouldRetry(err) {
+			if res != nil && policy != nil {
+				res.Attempts = attempts
+			}
+			return res, err
+		}
+
+		timer := time.NewTimer(policy.Backoff(number))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if res != nil {
+				res.Attempts = attempts
+			}
+			return res, err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryTransient(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	calls := 0
+	res, err := Retry(context.Background(), policy, func(_ context.Context, attempt int) (*Result, error) {
+		calls++
+		require.Equal(t, calls, attempt)
+		if attempt < 3 {
+			return &Result{}, StartError(errors.New("container name conflict"))
+		}
+		return &Result{}, nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 3, calls)
+	require.Len(t, res.Attempts, 3)
+	require.NotEmpty(t, res.Attempts[0].Error)
+	require.Empty(t, res.Attempts[2].Error)
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	exitErr := &NonZeroExitError{Code: 1}
+	run := func(policy *RetryPolicy) int {
+		calls := 0
+		_, _ = Retry(context.Background(), policy, func(context.Context, int) (*Result, error) {
+			calls++
+			return &Result{}, exitErr
+		})
+		return calls
+	}
+	require.Equal(t, 1, run(nil))
+	require.Equal(t, 1, run(&RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	require.Equal(t, 3, run(&RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, AllowNonIdempotent: true}))
+
+	// missing interpreters are never retried
+	policy := &RetryPolicy{MaxAttempts: 3}
+	require.False(t, policy.ShouldRetry(StartError(exec.ErrNotFound)))
+	require.False(t, policy.ShouldRetry(ErrPolicyViolation))
+	require.True(t, policy.ShouldRetry(BackendUnavailableError(nil)))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	require.Equal(t, 100*time.Millisecond, policy.Backoff(1))
+	require.Equal(t, 200*time.Millisecond, policy.Backoff(2))
+	require.Equal(t, 400*time.Millisecond, policy.Backoff(3))
+	require.Equal(t, time.Second, policy.Backoff(10))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.Backoff(1)
+		require.GreaterOrEqual(t, delay, 50*time.Millisecond)
+		require.LessOrEqual(t, delay, 150*time.Millisecond)
+	}
+}
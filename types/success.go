@@ -0,0 +1,63 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+// ErrUnexpectedOutcome is returned when an execution does not satisfy its success criteria
+var ErrUnexpectedOutcome = errors.New("execution outcome does not match success criteria")
+
+// StderrPolicy controls how output on stderr affects success
+type StderrPolicy uint8
+
+const (
+	// StderrIgnore does not consider stderr output
+	StderrIgnore StderrPolicy = iota
+	// StderrRequired fails executions which did not write to stderr
+	StderrRequired
+	// StderrForbidden fails executions which wrote to stderr
+	StderrForbidden
+)
+
+// SuccessCriteria declares which outcomes of an execution count as success.
+// A nil *SuccessCriteria keeps the default behavior (only exit code 0 succeeds)
+type SuccessCriteria struct {
+	// ExitCodes counting as success (defaults to 0 only)
+	ExitCodes []int
+	// Stderr policy applied to successful exit codes
+	Stderr StderrPolicy
+}
+
+// Check applies the criteria to the result and error of an execution.
+// Errors other than non-zero exits (timeouts, kills, start failures...) are
+// returned unchanged. Expected exit codes clear the error
+func (c *SuccessCriteria) Check(res *Result, err error) error {
+	if c == nil || res == nil {
+		return err
+	}
+	if err != nil && !errors.Is(err, ErrNonZeroExit) {
+		return err
+	}
+	codes := c.ExitCodes
+	if len(codes) == 0 {
+		codes = []int{0}
+	}
+	code := res.GetExitCode()
+	if !slices.Contains(codes, code) {
+		if err != nil {
+			return err
+		}
+		return errkit.Append(ErrUnexpectedOutcome, fmt.Errorf("exit code %d not in %v", code, codes))
+	}
+	switch {
+	case c.Stderr == StderrRequired && res.Stderr.Len() == 0:
+		return errkit.Append(ErrUnexpectedOutcome, errors.New("expected output on stderr"))
+	case c.Stderr == StderrForbidden && res.Stderr.Len() > 0:
+		return errkit.Append(ErrUnexpectedOutcome, fmt.Errorf("unexpected output on stderr: %s", res.Stderr.String()))
+	}
+	return nil
+}
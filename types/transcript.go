@@ -0,0 +1,195 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Stream is the output stream of a transcript chunk
+type Stream uint8
+
+const (
+	StreamStdout Stream = iota + 1
+	StreamStderr
+)
+
+// String returns the name of the stream
+func (s Stream) String() string {
+	switch s {
+	case StreamStdout:
+		return "stdout"
+	case StreamStderr:
+		return "stderr"
+	}
+	return fmt.Sprintf("stream(%d)", uint8(s))
+}
+
+// MarshalText encodes the stream as its name
+func (s Stream) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a stream name
+func (s *Stream) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "stdout":
+		*s = StreamStdout
+	case "stderr":
+		*s = StreamStderr
+	default:
+		return fmt.Errorf("unknown stream %q", text)
+	}
+	return nil
+}
+
+// Chunk is output written to a stream
+type Chunk struct {
+	Stream Stream
+	// Elapsed is the time since the start of the transcript
+	Elapsed time.Duration
+	Data    []byte
+}
+
+// chunkJSON encodes the data of a chunk as a string like the result output
+type chunkJSON struct {
+	Stream  Stream        `json:"stream"`
+	Elapsed time.Duration `json:"elapsed"`
+	Data    string        `json:"data"`
+}
+
+// Transcript records the output of an execution in the order it was read,
+// preserving the relative ordering of stdout and stderr. It is safe for concurrent use
+type Transcript struct {
+	mu     sync.Mutex
+	start  time.Time
+	chunks []Chunk
+}
+
+// NewTranscript returns a transcript whose elapsed times are measured from now
+// with the monotonic clock
+func NewTranscript() *Transcript {
+	return &Transcript{start: time.Now()}
+}
+
+// Record records a copy of data written to stream now
+func (t *Transcript) Record(stream Stream, data []byte) {
+	t.Append(Chunk{Stream: stream, Elapsed: time.Since(t.start), Data: data})
+}
+
+// Append records a copy of chunk, used by backends reporting their own
+// timestamps (e.g. docker logs). Chunks are kept in the order they are appended
+func (t *Transcript) Append(chunk Chunk) {
+	if len(chunk.Data) == 0 {
+		return
+	}
+	chunk.Data = bytes.Clone(chunk.Data)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.chunks = append(t.chunks, chunk)
+}
+
+// Writer returns a writer recording to stream
+func (t *Transcript) Writer(stream Stream) io.Writer {
+	return &transcriptWriter{transcript: t, stream: stream}
+}
+
+// Chunks returns the recorded chunks in order
+func (t *Transcript) Chunks() []Chunk {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Chunk{}, t.chunks...)
+}
+
+// Render writes the combined log of the transcript, one line per output
+// line prefixed by the elapsed time at which it started and its stream:
+//
+//	[   0.012s] stdout | hello
+//	[   0.013s] stderr | warning
+//
+// Consecutive chunks of a stream are joined, a line interrupted by another
+// stream is ended there
+func (t *Transcript) Render(w io.Writer) error {
+	var (
+		line    []byte
+		start   Chunk // stream and elapsed time of the line
+		pending bool  // a line started and was not ended yet
+	)
+	writeLine := func() error {
+		_, err := fmt.Fprintf(w, "[%9.3fs] %s | %s\n", start.Elapsed.Seconds(), start.Stream, bytes.TrimSuffix(line, []byte("\r")))
+		line, pending = line[:0], false
+		return err
+	}
+	for _, chunk := range t.Chunks() {
+		if pending && chunk.Stream != start.Stream {
+			if err := writeLine(); err != nil {
+				return err
+			}
+		}
+		data := chunk.Data
+		for len(data) > 0 {
+			if !pending {
+				start, pending = Chunk{Stream: chunk.Stream, Elapsed: chunk.Elapsed}, true
+			}
+			before, after, found := bytes.Cut(data, []byte("\n"))
+			line = append(line, before...)
+			if !found {
+				break
+			}
+			if err := writeLine(); err != nil {
+				return err
+			}
+			data = after
+		}
+	}
+	if pending {
+		return writeLine()
+	}
+	return nil
+}
+
+// String returns the combined log of the transcript (see Render)
+func (t *Transcript) String() string {
+	var buf bytes.Buffer
+	_ = t.Render(&buf)
+	return buf.String()
+}
+
+// MarshalJSON encodes the chunks of the transcript
+func (t *Transcript) MarshalJSON() ([]byte, error) {
+	chunks := t.Chunks()
+	v := make([]chunkJSON, 0, len(chunks))
+	for _, chunk := range chunks {
+		v = append(v, chunkJSON{Stream: chunk.Stream, Elapsed: chunk.Elapsed, Data: string(chunk.Data)})
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes chunks encoded with MarshalJSON
+func (t *Transcript) UnmarshalJSON(data []byte) error {
+	var v []chunkJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.chunks = nil
+	for _, chunk := range v {
+		t.chunks = append(t.chunks, Chunk{Stream: chunk.Stream, Elapsed: chunk.Elapsed, Data: []byte(chunk.Data)})
+	}
+	return nil
+}
+
+// transcriptWriter records writes to a stream of a transcript
+type transcriptWriter struct {
+	transcript *Transcript
+	stream     Stream
+}
+
+func (w *transcriptWriter) Write(p []byte) (int, error) {
+	w.transcript.Record(w.stream, p)
+	return len(p), nil
+}
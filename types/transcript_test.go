@@ -0,0 +1,53 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptRender(t *testing.T) {
+	transcript := NewTranscript()
+	for _, chunk := range []Chunk{
+		{Stream: StreamStdout, Elapsed: 10 * time.Millisecond, Data: []byte("hel")},
+		{Stream: StreamStdout, Elapsed: 20 * time.Millisecond, Data: []byte("lo\n\nwor")},
+		{Stream: StreamStderr, Elapsed: 1500 * time.Millisecond, Data: []byte("oops\r\n")},
+		{Stream: StreamStdout, Elapsed: 2 * time.Second, Data: []byte("ld\n")},
+		{Stream: StreamStderr, Elapsed: 3 * time.Second, Data: nil},
+	} {
+		transcript.Append(chunk)
+	}
+	require.Len(t, transcript.Chunks(), 4)
+	require.Equal(t, ""+
+		"[    0.010s] stdout | hello\n"+
+		"[    0.020s] stdout | \n"+
+		"[    0.020s] stdout | wor\n"+
+		"[    1.500s] stderr | oops\n"+
+		"[    2.000s] stdout | ld\n", transcript.String())
+
+	data, err := json.Marshal(&Result{Transcript: transcript})
+	require.Nil(t, err)
+	require.Contains(t, string(data), `{"stream":"stderr","elapsed":1500000000,"data":"oops\r\n"}`)
+	var decoded Result
+	require.Nil(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, transcript.Chunks(), decoded.Transcript.Chunks())
+}
+
+func TestTranscriptWriter(t *testing.T) {
+	transcript := NewTranscript()
+	data := []byte("out")
+	n, err := transcript.Writer(StreamStdout).Write(data)
+	require.Nil(t, err)
+	require.Equal(t, 3, n)
+	// written data is copied
+	data[0] = 'x'
+	_, _ = transcript.Writer(StreamStderr).Write([]byte("err"))
+
+	chunks := transcript.Chunks()
+	require.Len(t, chunks, 2)
+	require.Equal(t, "out", string(chunks[0].Data))
+	require.Equal(t, StreamStderr, chunks[1].Stream)
+	require.LessOrEqual(t, chunks[0].Elapsed, chunks[1].Elapsed)
+}